@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+// batchGetMoviesHandler fetches the movies with the ids listed in the request body. It's the
+// same as GET /v1/movies?ids=..., for lists of ids too long for a URL.
+func (app *application) batchGetMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	err := app.readRequest(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.writeMovieBatch(w, r, input.IDs)
+}
+
+// The writeMovieBatch() helper sends the movies with the given ids in the requested order,
+// fetched with a single query. The ids of the movies which don't exist, or are in the trash,
+// are listed in "missing" rather than failing the whole request.
+func (app *application) writeMovieBatch(w http.ResponseWriter, r *http.Request, ids []int64) {
+	v := validator.New()
+
+	v.Check(len(ids) != 0, "ids", "must be provided")
+	v.Check(len(ids) <= app.config.batch.maxIDs, "ids", fmt.Sprintf("must not contain more than %d ids", app.config.batch.maxIDs))
+	v.Check(validator.Unique(ids), "ids", "must not contain duplicate values")
+
+	for _, id := range ids {
+		if id < 1 {
+			v.AddError("ids", "must only contain positive integers")
+			break
+		}
+	}
+
+	if !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	found, err := app.models.Movies.GetMany(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movies := make([]*data.Movie, 0, len(found))
+	missing := []int64{}
+
+	for _, id := range ids {
+		if movie, ok := found[id]; ok {
+			movies = append(movies, movie)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	_, err = app.localizeTitles(w, r, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movies": newMovieViews(movies, format), "missing": missing}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBatchGetMoviesHandlerValidation(t *testing.T) {
+	// The ids are validated before the database is used, so an application without one is
+	// enough.
+	app := &application{}
+	app.config.batch.maxIDs = 3
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "missing ids", body: `{}`, want: "must be provided"},
+		{name: "empty ids", body: `{"ids": []}`, want: "must be provided"},
+		{name: "too many ids", body: `{"ids": [1, 2, 3, 4]}`, want: "must not contain more than 3 ids"},
+		{name: "duplicate ids", body: `{"ids": [1, 2, 1]}`, want: "must not contain duplicate values"},
+		{name: "zero id", body: `{"ids": [1, 0]}`, want: "must only contain positive integers"},
+		{name: "negative id", body: `{"ids": [-4]}`, want: "must only contain positive integers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/v1/movies/batch", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+
+			app.batchGetMoviesHandler(rr, r)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("got body %s; want it to contain %q", rr.Body, tt.want)
+			}
+		})
+	}
+}
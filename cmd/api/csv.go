@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The encodeCSV() helper encodes the JSON representation of v as CSV. The records are the
+// elements of the first array of the envelope, such as the movies of a list, or else its only
+// object, such as a single movie, or else the envelope itself. Nested objects are flattened
+// into "parent.child" columns, and arrays of scalars are joined with commas.
+func encodeCSV(v any) ([]byte, error) {
+	tree, err := toJSONTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []any
+
+	if envelope, ok := tree.(jsonObject); ok {
+		for _, member := range envelope {
+			if array, ok := member.Value.([]any); ok {
+				records = array
+				break
+			}
+		}
+
+		if records == nil {
+			records = []any{envelope}
+
+			if len(envelope) == 1 {
+				if object, ok := envelope[0].Value.(jsonObject); ok {
+					records = []any{object}
+				}
+			}
+		}
+	} else {
+		records = []any{tree}
+	}
+
+	var (
+		header []string
+		rows   = make([]map[string]string, len(records))
+	)
+
+	for i, record := range records {
+		rows[i] = map[string]string{}
+
+		flattenCSV(rows[i], &header, "", record)
+	}
+
+	var buf bytes.Buffer
+
+	cw := csv.NewWriter(&buf)
+
+	if len(header) > 0 {
+		err = cw.Write(header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, column := range header {
+			record[i] = row[column]
+		}
+
+		err = cw.Write(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cw.Flush()
+
+	return buf.Bytes(), cw.Error()
+}
+
+// flattenCSV adds the columns of a record to row, and the columns seen for the first time to
+// header.
+func flattenCSV(row map[string]string, header *[]string, column string, tree any) {
+	set := func(value string) {
+		if column == "" {
+			column = "value"
+		}
+		if !slices.Contains(*header, column) {
+			*header = append(*header, column)
+		}
+		row[column] = value
+	}
+
+	switch node := tree.(type) {
+	case jsonObject:
+		for _, member := range node {
+			key := member.Key
+			if column != "" {
+				key = column + "." + key
+			}
+			flattenCSV(row, header, key, member.Value)
+		}
+
+	case []any:
+		values := make([]string, len(node))
+
+		for i, value := range node {
+			switch value.(type) {
+			case jsonObject, []any:
+				// Arrays of objects don't fit in a column, so they are kept as JSON.
+				js, _ := json.Marshal(node)
+				set(string(js))
+				return
+			case nil:
+				values[i] = ""
+			default:
+				values[i] = fmt.Sprint(value)
+			}
+		}
+
+		set(strings.Join(values, ","))
+
+	case nil:
+		set("")
+
+	default:
+		set(fmt.Sprint(node))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestEncodeCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{
+			name: "records of the first array",
+			v: envelope{
+				"metadata": map[string]any{"total_records": 2},
+				"movies":   []any{map[string]any{"id": 1, "title": "Casablanca"}, map[string]any{"id": 2, "title": "Up, Up"}},
+			},
+			want: "id,title\n1,Casablanca\n2,\"Up, Up\"\n",
+		},
+		{
+			name: "single object",
+			v:    envelope{"movie": map[string]any{"id": 1, "title": "Casablanca"}},
+			want: "id,title\n1,Casablanca\n",
+		},
+		{
+			name: "envelope itself",
+			v:    envelope{"message": "deleted", "status": "ok"},
+			want: "message,status\ndeleted,ok\n",
+		},
+		{
+			name: "nested objects and arrays",
+			v: envelope{"movies": []any{
+				map[string]any{"genres": []string{"drama", "romance"}, "rating": map[string]any{"average": 4.5, "count": 2}, "credits": []any{map[string]any{"id": 7}}},
+				map[string]any{"genres": []string{}, "poster_url": nil},
+			}},
+			want: "credits,genres,rating.average,rating.count,poster_url\n\"[{\"\"id\"\":7}]\",\"drama,romance\",4.5,2,\n,,,,\n",
+		},
+		{
+			name: "empty list",
+			v:    envelope{"movies": []any{}},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeCSV(tt.v)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
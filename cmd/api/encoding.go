@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const responseEncoderContextKey = contextKey("responseEncoder")
+
+// A responseEncoder writes response bodies in one media type. The JSON encoder comes first, as
+// it is the default.
+type responseEncoder struct {
+	mediaType   string
+	aliases     []string
+	contentType string
+	encode      func(v any) ([]byte, error)
+}
+
+var responseEncoders = []*responseEncoder{
+	{mediaType: "application/json", contentType: "application/json", encode: encodeJSON},
+	{mediaType: "application/xml", aliases: []string{"text/xml"}, contentType: "application/xml; charset=utf-8", encode: encodeXML},
+	{mediaType: "text/csv", contentType: "text/csv; charset=utf-8", encode: encodeCSV},
+	{mediaType: "application/msgpack", aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}, contentType: "application/msgpack", encode: encodeMsgpackBody},
+}
+
+// requestMediaTypes lists the media types accepted in the Content-Type of request bodies. CSV
+// is left out, as it can't represent the objects most endpoints expect.
+var requestMediaTypes = []string{"application/json", "application/xml", "text/xml", "application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+
+// unsupportedMediaTypeError is returned by readRequest() for a body in a media type it can't
+// decode. badRequestResponse() answers it with a 415 Unsupported Media Type.
+type unsupportedMediaTypeError struct {
+	supported []string
+}
+
+func (e *unsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported Content-Type, expected one of: %s", strings.Join(e.supported, ", "))
+}
+
+// mediaTypes returns the media types of the encoder, the main one first.
+func (e *responseEncoder) mediaTypes() []string {
+	return append([]string{e.mediaType}, e.aliases...)
+}
+
+// The negotiate() middleware chooses the encoder of the responses from the Accept header of the
+// request, and stores it in the request context for writeResponse(). Requests accepting none of
+// the available media types are rejected before they are handled.
+func (app *application) negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		encoder, ok := negotiateEncoder(r.Header.Get("Accept"))
+		if !ok {
+			app.notAcceptableResponse(w, r)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), responseEncoderContextKey, encoder))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The contextGetEncoder() helper returns the encoder chosen by negotiate(), or the JSON encoder
+// for the routes which aren't negotiated, and for the responses rejecting the negotiation.
+func (app *application) contextGetEncoder(r *http.Request) *responseEncoder {
+	encoder, ok := r.Context().Value(responseEncoderContextKey).(*responseEncoder)
+	if !ok {
+		return responseEncoders[0]
+	}
+
+	return encoder
+}
+
+// negotiateEncoder returns the encoder preferred by an Accept header. Each encoder gets the
+// quality of the most specific media range which matches it. The encoder with the highest
+// quality wins, and ties go to the range listed first. An empty header accepts anything.
+func negotiateEncoder(accept string) (*responseEncoder, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return responseEncoders[0], true
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	var (
+		best        *responseEncoder
+		bestQuality float64
+		bestIndex   int
+	)
+
+	for _, encoder := range responseEncoders {
+		quality, index, specificity := 0.0, -1, -1
+
+		for i, rng := range ranges {
+			s := -1
+
+			for _, mediaType := range encoder.mediaTypes() {
+				switch {
+				case rng.mediaType == mediaType:
+					s = 2
+				case rng.mediaType == "*/*":
+					s = max(s, 0)
+				case strings.HasSuffix(rng.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rng.mediaType, "*")):
+					s = max(s, 1)
+				}
+			}
+
+			if s > specificity {
+				quality, index, specificity = rng.quality, i, s
+			}
+		}
+
+		if quality > 0 && (best == nil || quality > bestQuality || (quality == bestQuality && index < bestIndex)) {
+			best, bestQuality, bestIndex = encoder, quality, index
+		}
+	}
+
+	return best, best != nil
+}
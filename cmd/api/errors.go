@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 func (app *application) logError(r *http.Request, err error) {
@@ -21,8 +23,8 @@ func (app *application) rateLimitExccededResponse(w http.ResponseWriter, r *http
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
 
-// The errorResponse() method is a generic helper for sending JSON-formatted error
-// messages to the client with a given status code. Note that we're using the any
+// The errorResponse() method is a generic helper for sending error messages to the client,
+// in the negotiated format, with a given status code. Note that we're using the any
 // type for the message parameter, rather than just a string type, as this gives us
 // more flexibility over the values that we can include in the response.
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
@@ -30,7 +32,7 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 		"error": message,
 	}
 
-	err := app.writeJSON(w, status, env, nil)
+	err := app.writeResponse(w, r, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(500)
@@ -48,7 +50,15 @@ func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Reque
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+// The badRequestResponse() method sends a 400 Bad Request status code, or a 415 Unsupported
+// Media Type status code for the bodies readRequest() can't decode.
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var unsupportedMediaType *unsupportedMediaTypeError
+	if errors.As(err, &unsupportedMediaType) {
+		app.unsupportedMediaTypeResponse(w, r, unsupportedMediaType.supported...)
+		return
+	}
+
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
@@ -98,3 +108,35 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "your user account doesn't have the necessary permissions to access this ressource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, supported ...string) {
+	message := (&unsupportedMediaTypeError{supported: supported}).Error()
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+// The notAcceptableResponse() method is sent, as JSON, to the clients accepting none of the
+// formats responses can be written in.
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request) {
+	var mediaTypes []string
+	for _, encoder := range responseEncoders {
+		mediaTypes = append(mediaTypes, encoder.mediaTypes()...)
+	}
+
+	message := fmt.Sprintf("unsupported Accept header, responses are available in: %s", strings.Join(mediaTypes, ", "))
+	app.errorResponse(w, r, http.StatusNotAcceptable, message)
+}
+
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has been modified since the version given in the If-Match header"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this request must be made conditional with an If-Match header"
+	app.errorResponse(w, r, http.StatusPreconditionRequired, message)
+}
+
+func (app *application) genreInUseResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this genre is still used by some movies, remove it from them first"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"greenlight.hichammou/internal/data"
+)
+
+// movieETag returns the entity tag of the state of a movie, which the tags of its
+// representations are built upon. The version is incremented on every change made through the
+// API, so the id and the version identify most of the state. The poster and the aggregated
+// rating are updated without changing the version though, so they are hashed into the tag as
+// well.
+func movieETag(movie *data.Movie) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s\x00%v\x00%d", movie.PosterURL, movie.Rating.Average, movie.Rating.Count)
+
+	return fmt.Sprintf(`"%d-%d-%x"`, movie.ID, movie.Version, h.Sum32())
+}
+
+// representationETag returns the strong entity tag of an encoded representation of a movie.
+// It is the tag of the movie followed by a hash of the media type, the runtime format and the
+// body, so that each representation gets its own tag: the ones in other formats, and the ones
+// embedding related resources, localized titles or only some of the fields, which change
+// without the movie version.
+func representationETag(movie *data.Movie, mediaType, runtimeFormat string, body []byte) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s\x00%s\x00", mediaType, runtimeFormat)
+	h.Write(body)
+
+	return fmt.Sprintf(`%s-%x"`, strings.TrimSuffix(movieETag(movie), `"`), h.Sum32())
+}
+
+// matchesMovie reports whether an entity tag is the tag of the movie, or of one of its
+// representations.
+func matchesMovie(etag string, movie *data.Movie) bool {
+	tag := movieETag(movie)
+	if etag == tag {
+		return true
+	}
+
+	hash, ok := strings.CutPrefix(etag, strings.TrimSuffix(tag, `"`)+"-")
+	if !ok {
+		return false
+	}
+
+	_, err := strconv.ParseUint(strings.TrimSuffix(hash, `"`), 16, 32)
+	return err == nil && strings.HasSuffix(hash, `"`)
+}
+
+// etagListContains reports whether the value of an If-Match or If-None-Match header contains
+// a tag accepted by match. With weak set, W/ prefixed tags are compared as if they were
+// strong, as required for If-None-Match.
+func etagListContains(header string, match func(etag string) bool, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if match(candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The writeMovieResponse() helper sends a representation of a movie along with its entity
+// tag, and is used instead of writeResponse() by every handler sending a single movie. For a
+// GET request whose If-None-Match header matches the tag, it sends an empty 304 Not Modified
+// response instead.
+func (app *application) writeMovieResponse(w http.ResponseWriter, r *http.Request, status int, data envelope, movie *data.Movie, headers http.Header) error {
+	body, encoder, err := app.encodeResponse(r, data)
+	if err != nil {
+		return err
+	}
+
+	etag := representationETag(movie, encoder.mediaType, app.contextGetRuntimeFormat(r), body)
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("ETag", etag)
+
+	// Clients revalidating a cached copy get an empty 304 response if it hasn't changed.
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	matchesETag := func(candidate string) bool { return candidate == etag }
+
+	if r.Method == http.MethodGet && ifNoneMatch != "" && etagListContains(ifNoneMatch, matchesETag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", encoder.contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+
+	return nil
+}
+
+// The checkIfMatch() helper checks the If-Match header of a request modifying a movie. It
+// sends a 412 Precondition Failed response if the header doesn't match the current version
+// of the movie (or a 428 Precondition Required if the header is missing and the server is
+// configured to require it), and returns false in that case. The tag of any representation
+// of the current version matches, as they all describe the same movie.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, movie *data.Movie) bool {
+	ifMatch := r.Header.Get("If-Match")
+
+	if ifMatch == "" {
+		if app.config.etag.requireIfMatch {
+			app.preconditionRequiredResponse(w, r)
+			return false
+		}
+		return true
+	}
+
+	if !etagListContains(ifMatch, func(candidate string) bool { return matchesMovie(candidate, movie) }, false) {
+		app.preconditionFailedResponse(w, r)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"greenlight.hichammou/internal/data"
+)
+
+func TestEtagListContains(t *testing.T) {
+	match := func(etag string) bool { return etag == `"7-2-abc"` }
+
+	tests := []struct {
+		name   string
+		header string
+		weak   bool
+		want   bool
+	}{
+		{name: "single tag", header: `"7-2-abc"`, want: true},
+		{name: "list of tags", header: `"1-1-aaa", "7-2-abc" ,"9-9-fff"`, want: true},
+		{name: "wildcard", header: `*`, want: true},
+		{name: "other tag", header: `"7-1-abc"`, want: false},
+		{name: "weak tag compared strongly", header: `W/"7-2-abc"`, want: false},
+		{name: "weak tag compared weakly", header: `W/"7-2-abc"`, weak: true, want: true},
+		{name: "unquoted tag", header: `7-2-abc`, want: false},
+		{name: "empty", header: ``, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagListContains(tt.header, match, tt.weak); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepresentationETag(t *testing.T) {
+	movie := &data.Movie{ID: 7, Version: 2, Rating: data.MovieRating{Average: 4.5, Count: 2}}
+	body := []byte(`{"movie":{"id":7}}`)
+
+	tag := representationETag(movie, "application/json", data.RuntimeFormatMins, body)
+
+	if !matchesMovie(tag, movie) {
+		t.Errorf("%s doesn't match its movie", tag)
+	}
+	if !matchesMovie(movieETag(movie), movie) {
+		t.Errorf("%s doesn't match its movie", movieETag(movie))
+	}
+
+	others := map[string]string{
+		"media type":     representationETag(movie, "application/xml", data.RuntimeFormatMins, body),
+		"runtime format": representationETag(movie, "application/json", data.RuntimeFormatHM, body),
+		"body":           representationETag(movie, "application/json", data.RuntimeFormatMins, []byte(`{"movie":{"id":7,"title":"Up"}}`)),
+	}
+
+	for name, other := range others {
+		if other == tag {
+			t.Errorf("changing the %s doesn't change the tag %s", name, tag)
+		}
+		if !matchesMovie(other, movie) {
+			t.Errorf("the tag with another %s, %s, doesn't match its movie", name, other)
+		}
+	}
+
+	changed := map[string]*data.Movie{
+		"version": {ID: 7, Version: 3, Rating: movie.Rating},
+		"rating":  {ID: 7, Version: 2, Rating: data.MovieRating{Average: 4, Count: 3}},
+		"poster":  {ID: 7, Version: 2, Rating: movie.Rating, PosterURL: "7/a.jpg"},
+		"id":      {ID: 8, Version: 2, Rating: movie.Rating},
+	}
+
+	for name, other := range changed {
+		if matchesMovie(tag, other) {
+			t.Errorf("%s still matches the movie with another %s", tag, name)
+		}
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	movie := &data.Movie{ID: 7, Version: 2}
+	current := representationETag(movie, "application/json", data.RuntimeFormatMins, []byte("{}"))
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		require bool
+		ok      bool
+		status  int
+	}{
+		{name: "no header", ok: true},
+		{name: "required header", require: true, status: http.StatusPreconditionRequired},
+		{name: "current tag", ifMatch: current, ok: true},
+		{name: "tag of the movie", ifMatch: movieETag(movie), ok: true},
+		{name: "wildcard", ifMatch: "*", ok: true},
+		{name: "stale tag", ifMatch: movieETag(&data.Movie{ID: 7, Version: 1}), status: http.StatusPreconditionFailed},
+		{name: "weak tag", ifMatch: "W/" + current, status: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+			app.config.etag.requireIfMatch = tt.require
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "/v1/movies/7", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			if got := app.checkIfMatch(rr, r, movie); got != tt.ok {
+				t.Fatalf("got %t; want %t", got, tt.ok)
+			}
+
+			if !tt.ok && rr.Code != tt.status {
+				t.Errorf("got status %d; want %d", rr.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestWriteMovieResponseNotModified(t *testing.T) {
+	app := &application{}
+	movie := &data.Movie{ID: 7, Title: "Up", Version: 2}
+
+	write := func(method, ifNoneMatch string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/v1/movies/7", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		err := app.writeMovieResponse(rr, r, http.StatusOK, envelope{"movie": newMovieView(movie, data.RuntimeFormatMins)}, movie, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return rr
+	}
+
+	first := write(http.MethodGet, "")
+	etag := first.Header().Get("ETag")
+
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("got status %d and ETag %q; want 200 and a tag", first.Code, etag)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "same tag", method: http.MethodGet, ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "weak tag", method: http.MethodGet, ifNoneMatch: "W/" + etag, want: http.StatusNotModified},
+		{name: "other tag", method: http.MethodGet, ifNoneMatch: movieETag(movie), want: http.StatusOK},
+		{name: "not a GET", method: http.MethodPatch, ifNoneMatch: etag, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := write(tt.method, tt.ifNoneMatch)
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d", rr.Code, tt.want)
+			}
+			if rr.Header().Get("ETag") != etag {
+				t.Errorf("got ETag %q; want %q", rr.Header().Get("ETag"), etag)
+			}
+			if tt.want == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("got a body with a 304 response: %s", rr.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+// exportFlushEvery is the number of rows written between two flushes of the response.
+const exportFlushEvery = 500
+
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.MovieSearch
+		Format string
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	search, err := app.readMovieSearch(qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	input.MovieSearch = search
+	input.Format = app.readString(qs, "format", "ndjson")
+
+	if v.Check(validator.In(input.Format, "ndjson", "csv"), "format", "must be one of ndjson, csv"); !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// An export of the whole catalog can take longer than the server's WriteTimeout, so
+	// lift the write deadline for this response only.
+	err = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	buf := bufio.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+
+	var (
+		header   []string
+		writeRow func(*data.Movie) error
+		finish   func() error
+	)
+
+	switch input.Format {
+	case "csv":
+		cw := csv.NewWriter(buf)
+		header = []string{"id", "title", "year", "runtime", "genres", "version"}
+		writeRow = func(movie *data.Movie) error {
+			// A nil movie writes the header record.
+			if movie == nil {
+				return cw.Write(header)
+			}
+			return cw.Write([]string{
+				strconv.FormatInt(movie.ID, 10),
+				movie.Title,
+				strconv.Itoa(int(movie.Year)),
+				strconv.Itoa(int(movie.Runtime)),
+				strings.Join(movie.Genres, ","),
+				strconv.Itoa(int(movie.Version)),
+			})
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		enc := json.NewEncoder(buf)
+		writeRow = func(movie *data.Movie) error {
+			return enc.Encode(movie)
+		}
+		finish = func() error { return nil }
+	}
+
+	// start sends the headers and, for CSV, the header record. It's called with the first row
+	// so that a failure to open the snapshot can still be reported with a regular error response.
+	start := func() error {
+		app.writeExportHeaders(w, input.Format)
+
+		if header != nil {
+			return writeRow(nil)
+		}
+		return nil
+	}
+
+	written := 0
+
+	err = app.models.Movies.Export(r.Context(), input.MovieSearch, func(movie *data.Movie) error {
+		if written == 0 {
+			err := start()
+			if err != nil {
+				return err
+			}
+		}
+
+		written++
+
+		err := writeRow(movie)
+		if err != nil {
+			return err
+		}
+
+		if written%exportFlushEvery == 0 {
+			err = finish()
+			if err == nil {
+				err = buf.Flush()
+			}
+			if err == nil && flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		return err
+	})
+
+	if err != nil {
+		if written == 0 {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// The status code has already been sent, the best we can do is to log the error
+		// and cut the stream short.
+		app.logError(r, err)
+		return
+	}
+
+	if written == 0 {
+		err = start()
+	}
+	if err == nil {
+		err = finish()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+func (app *application) writeExportHeaders(w http.ResponseWriter, format string) {
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="movies.csv"`)
+	default:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="movies.ndjson"`)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExportMoviesHandlerValidation(t *testing.T) {
+	app := &application{}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "unknown format", query: "format=xml"},
+		{name: "json isn't a stream", query: "format=json"},
+		{name: "invalid search", query: "format=csv&year_min=soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/movies/export?"+tt.query, nil)
+
+			app.exportMoviesHandler(rr, r)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+		})
+	}
+}
+
+func TestWriteExportHeaders(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		disposition string
+	}{
+		{format: "ndjson", contentType: "application/x-ndjson", disposition: `attachment; filename="movies.ndjson"`},
+		{format: "csv", contentType: "text/csv", disposition: `attachment; filename="movies.csv"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			(&application{}).writeExportHeaders(rr, tt.format)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusOK)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("got Content-Type %q; want %q", got, tt.contentType)
+			}
+			if got := rr.Header().Get("Content-Disposition"); got != tt.disposition {
+				t.Errorf("got Content-Disposition %q; want %q", got, tt.disposition)
+			}
+		})
+	}
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"greenlight.hichammou/internal/data"
 	"greenlight.hichammou/internal/validator"
 )
 
@@ -25,80 +29,120 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// The readVersionParam() helper reads the ":version" URL parameter used by the revision routes.
+func (app *application) readVersionParam(r *http.Request) (int32, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	version, err := strconv.ParseInt(params.ByName("version"), 10, 32)
+	if err != nil || version < 1 {
+		return 0, errors.New("invalid version parameter")
+	}
+
+	return int32(version), nil
+}
+
 type envelope map[string]any
 
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	js, err := json.MarshalIndent(data, "", "\t")
+// The writeResponse() helper sends the response in the format negotiated with the Accept
+// header of the request, JSON by default.
+func (app *application) writeResponse(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
+	body, encoder, err := app.encodeResponse(r, data)
 	if err != nil {
 		return err
 	}
 
-	// append a new line at the end of json for terminal apps
-	js = append(js, '\n')
-
 	// maps.Insert(w.Header(), maps.All(headers))
 
 	for key, value := range headers {
 		w.Header()[key] = value
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", encoder.contentType)
 	w.WriteHeader(status)
-	w.Write(js)
+	w.Write(body)
 
 	return nil
 }
 
-func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+// The encodeResponse() helper encodes a response body with the encoder negotiated for the
+// request, which it returns along with the body.
+func (app *application) encodeResponse(r *http.Request, data envelope) ([]byte, *responseEncoder, error) {
+	encoder := app.contextGetEncoder(r)
+
+	out, err := encoder.encode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, encoder, nil
+}
+
+// The readRequest() helper decodes the request body into dst, in the format given by the
+// Content-Type of the request: JSON, which is the default, XML or MessagePack. It returns an
+// *unsupportedMediaTypeError for any other format.
+func (app *application) readRequest(w http.ResponseWriter, r *http.Request, dst any) error {
 	// Use http.MaxBytesReader() to limit the size of the request body to 1MB
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
 
-	dec := json.NewDecoder(r.Body)
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
 
-	// DisallowUnknownFields() tels the decoder to return an error if JSON in the request body contains any fields that can not be mapped to target destination
-	dec.DisallowUnknownFields()
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return &unsupportedMediaTypeError{supported: requestMediaTypes}
+		}
+	}
 
-	err := dec.Decode(dst)
+	switch mediaType {
+	case "application/json":
+		return decodeJSONBody(r.Body, dst, int64(maxBytes))
 
-	if err != nil {
-		var (
-			syntaxError            *json.SyntaxError
-			unmarshalTypeError     *json.UnmarshalTypeError
-			invalidUnmarshaldError *json.InvalidUnmarshalError
-		)
-
-		switch {
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
-
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains badly-formed JSON")
-
-		case errors.As(err, &unmarshalTypeError):
-			if unmarshalTypeError.Field != "" {
-				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
-			}
-			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+	case "application/xml", "text/xml", "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return decodeJSONError(err, int64(maxBytes))
+		}
 
-		case errors.Is(err, io.EOF):
+		if len(body) == 0 {
 			return errors.New("body must not be empty")
+		}
 
-		// We check if the decoder found a field that can not be mapped to dst, then extract the field name from the error
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
-
-		// Check if the request exceeds 1MB
-		case err.Error() == "http: request body too large":
-			return fmt.Errorf("body must not be larger that %d bytes", maxBytes)
+		// The body is converted to JSON, so that it's decoded into dst as strictly as a JSON body.
+		var js []byte
 
-		case errors.As(err, &invalidUnmarshaldError):
-			panic(err)
+		if strings.HasSuffix(mediaType, "xml") {
+			js, err = decodeXML(body, dst)
+		} else {
+			var value any
 
-		default:
+			value, err = decodeMsgpack(body)
+			if err == nil {
+				js, err = json.Marshal(value)
+			}
+		}
+		if err != nil {
 			return err
 		}
+
+		return decodeJSONBody(bytes.NewReader(js), dst, int64(maxBytes))
+
+	default:
+		return &unsupportedMediaTypeError{supported: requestMediaTypes}
+	}
+}
+
+// The decodeJSONBody() helper strictly decodes a single JSON value into dst.
+func decodeJSONBody(body io.Reader, dst any, maxBytes int64) error {
+	dec := json.NewDecoder(body)
+
+	// DisallowUnknownFields() tels the decoder to return an error if JSON in the request body contains any fields that can not be mapped to target destination
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		return decodeJSONError(err, maxBytes)
 	}
 
 	// Here we called Decode() again to check if the request body contains only a single JSON value. if not we return an error
@@ -110,6 +154,97 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// The decodeJSONError() helper translates an error returned by json.Decoder.Decode() into
+// a client friendly message. It is shared by readRequest() and the streaming decoders used by
+// the import endpoint.
+func decodeJSONError(err error, maxBytes int64) error {
+	var (
+		syntaxError            *json.SyntaxError
+		unmarshalTypeError     *json.UnmarshalTypeError
+		invalidUnmarshaldError *json.InvalidUnmarshalError
+	)
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains badly-formed JSON")
+
+	case errors.As(err, &unmarshalTypeError):
+		if unmarshalTypeError.Field != "" {
+			return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+		}
+		return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
+
+	// We check if the decoder found a field that can not be mapped to dst, then extract the field name from the error
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Errorf("body contains unknown key %s", fieldName)
+
+	// Check if the request exceeds the maxBytes limit
+	case err.Error() == "http: request body too large":
+		return fmt.Errorf("body must not be larger that %d bytes", maxBytes)
+
+	case errors.As(err, &invalidUnmarshaldError):
+		panic(err)
+
+	default:
+		return err
+	}
+}
+
+// The sparseFields() helper trims the JSON object of v down to the given fields, so that the
+// fields which weren't requested, and were left empty, aren't sent at all. It returns v
+// unchanged if fields is empty.
+func sparseFields(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]json.RawMessage
+
+	err = json.Unmarshal(js, &object)
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range object {
+		if !slices.Contains(fields, key) {
+			delete(object, key)
+		}
+	}
+
+	return object, nil
+}
+
+// The sparseFieldsAll() helper applies sparseFields() to every value of a slice.
+func sparseFieldsAll[T any](values []T, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return values, nil
+	}
+
+	objects := make([]any, len(values))
+
+	for i, v := range values {
+		object, err := sparseFields(v, fields)
+		if err != nil {
+			return nil, err
+		}
+		objects[i] = object
+	}
+
+	return objects, nil
+}
+
 // The readString() helper returns a string value from the query string, or the provided
 // default value if no matching key could be found.
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
@@ -139,6 +274,81 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
+// The readRuntime() helper reads a runtime from the query string, in any of the forms accepted
+// by data.ParseRuntime(). If no matching key could be found it returns the provided default
+// value. If the value couldn't be parsed, it records an error message in the provided
+// Validator instance.
+func (app *application) readRuntime(qs url.Values, key string, defaultValue data.Runtime, v *validator.Validator) data.Runtime {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	runtime, err := data.ParseRuntime(s)
+	if err != nil {
+		v.AddError(key, err.Error())
+		return defaultValue
+	}
+
+	return runtime
+}
+
+// The readIDs() helper reads a comma-separated list of ids from the query string. If a value
+// isn't an integer, it records an error message in the provided Validator instance.
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	values := app.readCSV(qs, key, []string{})
+
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			v.AddError(key, "must only contain integer values")
+			return nil
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// The readMovieSearch() helper reads the query string parameters used to filter every list of
+// movies, and records any validation error in the provided Validator instance.
+func (app *application) readMovieSearch(qs url.Values, v *validator.Validator) (data.MovieSearch, error) {
+	search := data.MovieSearch{
+		Title:         app.readString(qs, "title", ""),
+		Genres:        app.readCSV(qs, "genres", []string{}),
+		ExcludeGenres: app.readCSV(qs, "exclude_genres", []string{}),
+		YearMin:       app.readInt32(qs, "year_min", 0, v),
+		YearMax:       app.readInt32(qs, "year_max", 0, v),
+		RuntimeMin:    int32(app.readRuntime(qs, "runtime_min", 0, v)),
+		RuntimeMax:    int32(app.readRuntime(qs, "runtime_max", 0, v)),
+		PersonID:      int64(app.readInt(qs, "person_id", 0, v)),
+		CollectionID:  int64(app.readInt(qs, "collection_id", 0, v)),
+	}
+
+	// Movies reference genres by slug, so accept any spelling of it, and the aliases of genres
+	// like when saving a movie. Unknown genres are searched for as is, and match no movie.
+	if len(search.Genres) > 0 || len(search.ExcludeGenres) > 0 {
+		taxonomy, err := app.models.Genres.Taxonomy()
+		if err != nil {
+			return data.MovieSearch{}, err
+		}
+
+		for _, genres := range [][]string{search.Genres, search.ExcludeGenres} {
+			for i, genre := range genres {
+				genres[i] = data.GenreSlug(genre)
+				if slug, ok := taxonomy.Resolve(genre); ok {
+					genres[i] = slug
+				}
+			}
+		}
+	}
+
+	data.ValidateMovieSearch(v, search)
+
+	return search, nil
+}
+
 // The readInt() helper reads a string value from the query string and converts it to an
 // integer before returning. If no matching key could be found it returns the provided
 // default value. If the value couldn't be converted to an integer, then we record an
@@ -161,6 +371,46 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// The readInt32() helper is like readInt() for the values stored in an int32, such as a year. It
+// records an error message in the provided Validator instance for the values out of that range,
+// rather than letting them wrap around.
+func (app *application) readInt32(qs url.Values, key string, defaultValue int32, v *validator.Validator) int32 {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.ParseInt(s, 10, 32)
+	if errors.Is(err, strconv.ErrRange) {
+		v.AddError(key, "must be between -2147483648 and 2147483647")
+		return defaultValue
+	}
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return int32(i)
+}
+
+// The readBool() helper reads an optional boolean value from the query string. It returns nil
+// if no matching key could be found, and records an error message in the provided Validator
+// instance if the value couldn't be converted to a boolean.
+func (app *application) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return nil
+	}
+
+	return &b
+}
+
 // The background() helper accepts an arbitrary function as a parameter.
 func (app *application) background(fn func()) {
 	// Increment the WaitGroup counter.
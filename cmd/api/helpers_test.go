@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestReadMovieSearch(t *testing.T) {
+	app := &application{}
+
+	tests := []struct {
+		query   string
+		want    data.MovieSearch
+		key     string
+		message string
+	}{
+		{query: "year_min=1990&year_max=1999", want: data.MovieSearch{YearMin: 1990, YearMax: 1999}},
+		{query: "runtime_min=90&runtime_max=2h", want: data.MovieSearch{RuntimeMin: 90, RuntimeMax: 120}},
+		{query: "year_min=nineties", key: "year_min", message: "must be an integer value"},
+		{query: "year_max=4294969295", key: "year_max", message: "must be between -2147483648 and 2147483647"},
+		{query: "runtime_min=4294967386", key: "runtime_min"},
+		{query: "year_min=2000&year_max=1990", key: "year_min", message: "must not be greater than year_max"},
+		{query: "runtime_min=2h&runtime_max=90", key: "runtime_min", message: "must not be greater than runtime_max"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			qs, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := validator.New()
+
+			search, err := app.readMovieSearch(qs, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.key == "" {
+				if !v.Valide() {
+					t.Fatalf("got errors %v; want none", v.Errors)
+				}
+
+				if search.YearMin != tt.want.YearMin || search.YearMax != tt.want.YearMax ||
+					search.RuntimeMin != tt.want.RuntimeMin || search.RuntimeMax != tt.want.RuntimeMax {
+					t.Errorf("got search %+v; want %+v", search, tt.want)
+				}
+				return
+			}
+
+			message, ok := v.Errors[tt.key]
+			if !ok {
+				t.Fatalf("got errors %v; want an error for %q", v.Errors, tt.key)
+			}
+
+			if tt.message != "" && message != tt.message {
+				t.Errorf("got %q for %q; want %q", message, tt.key, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+// importRow holds a single record of an import body. It mirrors the input accepted by
+// createMovieHandler.
+type importRow struct {
+	Title   string       `json:"title"`
+	Year    int32        `json:"year"`
+	Runtime data.Runtime `json:"runtime"`
+	Genres  []string     `json:"genres"`
+}
+
+type importRowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+type importReport struct {
+	TotalRows int              `json:"total_rows"`
+	Imported  int              `json:"imported"`
+	Rejected  int              `json:"rejected"`
+	Errors    []importRowError `json:"errors"`
+	Aborted   string           `json:"aborted,omitempty"`
+
+	// When saving a batch fails, Failed counts the valid rows of that batch and FailedFromRow
+	// is its first row. Every row before it was either imported or rejected, and no row after
+	// it was, so the import can be resumed from that row.
+	Failed        int `json:"failed,omitempty"`
+	FailedFromRow int `json:"failed_from_row,omitempty"`
+}
+
+// A rowDecoder calls fn once for every record in the body. decodeErr is non-nil when the
+// record itself could not be decoded, in that case the row is reported and skipped.
+type rowDecoder func(body io.Reader, fn func(row int, input *importRow, decodeErr map[string]string) error) error
+
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var decode rowDecoder
+
+	switch mediaType {
+	case "application/json":
+		decode = app.decodeJSONArrayRows
+	case "application/x-ndjson", "application/ndjson":
+		decode = app.decodeNDJSONRows
+	case "text/csv":
+		decode = app.decodeCSVRows
+	default:
+		app.unsupportedMediaTypeResponse(w, r, "application/json", "application/x-ndjson", "text/csv")
+		return
+	}
+
+	// Load the genre taxonomy once, rather than for every row.
+	genres, err := app.models.Genres.Taxonomy()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The import body is read as a stream, so we only need to cap its total size.
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.importer.maxBytes)
+
+	user := app.contextGetUser(r)
+
+	report := importReport{Errors: []importRowError{}}
+	batch := make([]*data.Movie, 0, app.config.importer.batchSize)
+	batchRow := 0 // the row of the first movie of the batch
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := app.models.Movies.InsertBatch(batch, user.ID)
+		if err != nil {
+			return err
+		}
+
+		report.Imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err = decode(r.Body, func(row int, input *importRow, decodeErr map[string]string) error {
+		report.TotalRows++
+
+		if decodeErr != nil {
+			report.Rejected++
+			report.Errors = append(report.Errors, importRowError{Row: row, Errors: decodeErr})
+			return nil
+		}
+
+		movie := &data.Movie{
+			Title:   input.Title,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+			Genres:  input.Genres,
+		}
+
+		v := validator.New()
+
+		if data.ValidateMovie(v, movie, genres); !v.Valide() {
+			report.Rejected++
+			report.Errors = append(report.Errors, importRowError{Row: row, Errors: v.Errors})
+			return nil
+		}
+
+		if len(batch) == 0 {
+			batchRow = row
+		}
+
+		batch = append(batch, movie)
+		if len(batch) >= app.config.importer.batchSize {
+			return flush()
+		}
+
+		return nil
+	})
+
+	// A body that can't be read any further ends the import, but the valid rows read so far
+	// are still inserted and reported back to the client.
+	var abortErr *importAbortError
+	switch {
+	case errors.As(err, &abortErr):
+		report.Aborted = abortErr.Error()
+	case err != nil:
+		app.importFailedResponse(w, r, &report, len(batch), batchRow, err)
+		return
+	}
+
+	err = flush()
+	if err != nil {
+		app.importFailedResponse(w, r, &report, len(batch), batchRow, err)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Aborted != "" {
+		status = http.StatusBadRequest
+	}
+
+	err = app.writeResponse(w, r, status, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The importFailedResponse() method reports a batch which couldn't be saved. The batches saved
+// before it stay committed, so the report is sent along with the error for the client to know
+// from which row to resume the import, rather than importing the same movies twice.
+func (app *application) importFailedResponse(w http.ResponseWriter, r *http.Request, report *importReport, failed, failedFromRow int, err error) {
+	app.logError(r, err)
+
+	report.Failed = failed
+	report.FailedFromRow = failedFromRow
+	report.Aborted = fmt.Sprintf("row %d: the server encountered a problem and could not save the rows from this one", failedFromRow)
+
+	env := envelope{
+		"error":  "the server encountered a problem and could not process your request",
+		"report": report,
+	}
+
+	err = app.writeResponse(w, r, http.StatusInternalServerError, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// importAbortError is returned by the row decoders when the body can not be read any further
+// (badly-formed JSON array, body too large...). Rows processed before that point are kept.
+type importAbortError struct {
+	row    int
+	reason error
+}
+
+func (e *importAbortError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.row, e.reason)
+}
+
+// rowDecodeErrors converts a per-row JSON decoding error into the map format used by
+// validator.Errors, so that it can be reported like a validation failure.
+func (app *application) rowDecodeErrors(err error) map[string]string {
+	if errors.Is(err, data.ErrInvalidRuntimeFormat) {
+		return map[string]string{"runtime": err.Error()}
+	}
+
+	return map[string]string{"body": decodeJSONError(err, app.config.importer.maxBytes).Error()}
+}
+
+// isFatalJSONError reports whether a decoding error leaves the decoder unable to read the
+// rest of the stream.
+func isFatalJSONError(err error) bool {
+	var (
+		syntaxError   *json.SyntaxError
+		maxBytesError *http.MaxBytesError
+	)
+
+	return errors.As(err, &syntaxError) || errors.As(err, &maxBytesError) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// decodeJSONArrayRows reads a body of the form [{...}, {...}] one element at a time.
+func (app *application) decodeJSONArrayRows(body io.Reader, fn func(int, *importRow, map[string]string) error) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return &importAbortError{row: 0, reason: decodeJSONError(err, app.config.importer.maxBytes)}
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return &importAbortError{row: 0, reason: errors.New("body must contain a JSON array")}
+	}
+
+	row := 0
+	for dec.More() {
+		row++
+
+		var input importRow
+
+		err := dec.Decode(&input)
+		switch {
+		case err == nil:
+			err = fn(row, &input, nil)
+		case isFatalJSONError(err):
+			return &importAbortError{row: row, reason: decodeJSONError(err, app.config.importer.maxBytes)}
+		default:
+			err = fn(row, nil, app.rowDecodeErrors(err))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing bracket, this also catches a truncated body.
+	_, err = dec.Token()
+	if err != nil {
+		return &importAbortError{row: row, reason: decodeJSONError(err, app.config.importer.maxBytes)}
+	}
+
+	return nil
+}
+
+// decodeNDJSONRows reads a body containing one JSON object per line. A badly-formed line is
+// reported as a rejected row and doesn't stop the import.
+func (app *application) decodeNDJSONRows(body io.Reader, fn func(int, *importRow, map[string]string) error) error {
+	reader := bufio.NewReader(body)
+
+	row := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return &importAbortError{row: row + 1, reason: decodeJSONError(readErr, app.config.importer.maxBytes)}
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			row++
+
+			var input importRow
+
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.DisallowUnknownFields()
+
+			var err error
+			if decErr := dec.Decode(&input); decErr != nil {
+				err = fn(row, nil, app.rowDecodeErrors(decErr))
+			} else if dec.More() {
+				err = fn(row, nil, map[string]string{"body": "line must contain only one single JSON value"})
+			} else {
+				err = fn(row, &input, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// decodeCSVRows reads a CSV body whose first record is a header naming the title, year,
+// runtime and genres columns. Genres are separated by commas inside their field, and the
+// runtime is either a number of minutes or a "<n> mins" string.
+func (app *application) decodeCSVRows(body io.Reader, fn func(int, *importRow, map[string]string) error) error {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return &importAbortError{row: 0, reason: errors.New("body must not be empty")}
+		}
+		return &importAbortError{row: 0, reason: app.csvReadError(err)}
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !validator.In(name, "title", "year", "runtime", "genres") {
+			return &importAbortError{row: 0, reason: fmt.Errorf("header contains unknown column %q", name)}
+		}
+		columns[name] = i
+	}
+
+	for _, name := range []string{"title", "year", "runtime", "genres"} {
+		if _, ok := columns[name]; !ok {
+			return &importAbortError{row: 0, reason: fmt.Errorf("header must contain a %q column", name)}
+		}
+	}
+
+	row := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		row++
+
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount):
+			err = fn(row, nil, map[string]string{"body": fmt.Sprintf("record must contain %d fields", len(header))})
+		case err != nil:
+			return &importAbortError{row: row, reason: app.csvReadError(err)}
+		default:
+			input, errs := parseCSVRecord(record, columns)
+			err = fn(row, input, errs)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (app *application) csvReadError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return fmt.Errorf("body must not be larger that %d bytes", app.config.importer.maxBytes)
+	}
+	return fmt.Errorf("body contains badly-formed CSV: %w", err)
+}
+
+func parseCSVRecord(record []string, columns map[string]int) (*importRow, map[string]string) {
+	v := validator.New()
+
+	input := &importRow{
+		Title: record[columns["title"]],
+	}
+
+	if s := strings.TrimSpace(record[columns["year"]]); s != "" {
+		year, err := strconv.ParseInt(s, 10, 32)
+		v.Check(err == nil, "year", "must be an integer value")
+		input.Year = int32(year)
+	}
+
+	if s := strings.TrimSpace(record[columns["runtime"]]); s != "" {
+		runtime, err := data.ParseRuntime(s)
+		if err != nil {
+			v.AddError("runtime", err.Error())
+		}
+		input.Runtime = runtime
+	}
+
+	if s := strings.TrimSpace(record[columns["genres"]]); s != "" {
+		input.Genres = []string{}
+		for _, genre := range strings.Split(s, ",") {
+			input.Genres = append(input.Genres, strings.TrimSpace(genre))
+		}
+	}
+
+	if !v.Valide() {
+		return nil, v.Errors
+	}
+
+	return input, nil
+}
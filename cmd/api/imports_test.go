@@ -0,0 +1,347 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodedRow records a call of the function given to a rowDecoder.
+type decodedRow struct {
+	row   int
+	input *importRow
+	errs  map[string]string
+}
+
+// decodeRows runs a rowDecoder over body, returning the rows it decoded and the row of the
+// importAbortError it returned, or -1 if it didn't abort.
+func decodeRows(t *testing.T, decode rowDecoder, body string) ([]decodedRow, int) {
+	t.Helper()
+
+	var rows []decodedRow
+
+	err := decode(strings.NewReader(body), func(row int, input *importRow, decodeErr map[string]string) error {
+		rows = append(rows, decodedRow{row: row, input: input, errs: decodeErr})
+		return nil
+	})
+
+	var abortErr *importAbortError
+	switch {
+	case errors.As(err, &abortErr):
+		return rows, abortErr.row
+	case err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return rows, -1
+}
+
+// checkRows compares decoded rows with the wanted ones. A wanted row with errors only checks
+// the keys of the errors, not the messages.
+func checkRows(t *testing.T, got, want []decodedRow) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows; want %d: %+v", len(got), len(want), got)
+	}
+
+	for i := range want {
+		if got[i].row != want[i].row {
+			t.Errorf("row %d: got row number %d", want[i].row, got[i].row)
+		}
+
+		if !reflect.DeepEqual(got[i].input, want[i].input) {
+			t.Errorf("row %d: got input %+v; want %+v", want[i].row, got[i].input, want[i].input)
+		}
+
+		if len(got[i].errs) != len(want[i].errs) {
+			t.Errorf("row %d: got errors %v; want errors for %v", want[i].row, got[i].errs, want[i].errs)
+			continue
+		}
+		for key := range want[i].errs {
+			if _, ok := got[i].errs[key]; !ok {
+				t.Errorf("row %d: got errors %v; want an error for %q", want[i].row, got[i].errs, key)
+			}
+		}
+	}
+}
+
+func newImportTestApplication() *application {
+	app := &application{}
+	app.config.importer.maxBytes = 1 << 20
+	return app
+}
+
+var casablanca = &importRow{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama", "romance"}}
+
+func TestDecodeJSONArrayRows(t *testing.T) {
+	app := newImportTestApplication()
+
+	tests := []struct {
+		name      string
+		body      string
+		want      []decodedRow
+		abortedAt int
+	}{
+		{
+			name:      "rows",
+			body:      `[{"title":"Casablanca","year":1942,"runtime":"102 mins","genres":["drama","romance"]}, {"title":"Up","runtime":96}]`,
+			want:      []decodedRow{{row: 1, input: casablanca}, {row: 2, input: &importRow{Title: "Up", Runtime: 96}}},
+			abortedAt: -1,
+		},
+		{
+			name:      "empty array",
+			body:      `[]`,
+			abortedAt: -1,
+		},
+		{
+			name: "rejected rows",
+			body: `[{"title":"Up","rating":5}, {"title":"Up","runtime":"soon"}, {"title":7}, {"title":"Up"}]`,
+			want: []decodedRow{
+				{row: 1, errs: map[string]string{"body": ""}},
+				{row: 2, errs: map[string]string{"runtime": ""}},
+				{row: 3, errs: map[string]string{"body": ""}},
+				{row: 4, input: &importRow{Title: "Up"}},
+			},
+			abortedAt: -1,
+		},
+		{
+			name:      "truncated",
+			body:      `[{"title":"Up"}, {"title":"Casa`,
+			want:      []decodedRow{{row: 1, input: &importRow{Title: "Up"}}},
+			abortedAt: 2,
+		},
+		{
+			name:      "missing closing bracket",
+			body:      `[{"title":"Up"}`,
+			want:      []decodedRow{{row: 1, input: &importRow{Title: "Up"}}},
+			abortedAt: 2,
+		},
+		{
+			name:      "not an array",
+			body:      `{"title":"Up"}`,
+			abortedAt: 0,
+		},
+		{
+			name:      "empty body",
+			body:      ``,
+			abortedAt: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, abortedAt := decodeRows(t, app.decodeJSONArrayRows, tt.body)
+
+			if abortedAt != tt.abortedAt {
+				t.Errorf("aborted at row %d; want %d", abortedAt, tt.abortedAt)
+			}
+
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestDecodeNDJSONRows(t *testing.T) {
+	app := newImportTestApplication()
+
+	tests := []struct {
+		name string
+		body string
+		want []decodedRow
+	}{
+		{
+			name: "rows",
+			body: "{\"title\":\"Casablanca\",\"year\":1942,\"runtime\":102,\"genres\":[\"drama\",\"romance\"]}\n{\"title\":\"Up\"}\n",
+			want: []decodedRow{{row: 1, input: casablanca}, {row: 2, input: &importRow{Title: "Up"}}},
+		},
+		{
+			name: "blank lines and no final newline",
+			body: "\n{\"title\":\"Up\"}\r\n  \n{\"title\":\"Heat\"}",
+			want: []decodedRow{{row: 1, input: &importRow{Title: "Up"}}, {row: 2, input: &importRow{Title: "Heat"}}},
+		},
+		{
+			name: "truncated line",
+			body: "{\"title\":\"Casa\n{\"title\":\"Up\"}\n",
+			want: []decodedRow{{row: 1, errs: map[string]string{"body": ""}}, {row: 2, input: &importRow{Title: "Up"}}},
+		},
+		{
+			name: "truncated last line",
+			body: "{\"title\":\"Up\"}\n{\"title\":",
+			want: []decodedRow{{row: 1, input: &importRow{Title: "Up"}}, {row: 2, errs: map[string]string{"body": ""}}},
+		},
+		{
+			name: "two values on a line",
+			body: "{\"title\":\"Up\"} {\"title\":\"Heat\"}\n",
+			want: []decodedRow{{row: 1, errs: map[string]string{"body": ""}}},
+		},
+		{
+			name: "unknown field and bad runtime",
+			body: "{\"title\":\"Up\",\"rating\":5}\n{\"runtime\":\"soon\"}\n",
+			want: []decodedRow{{row: 1, errs: map[string]string{"body": ""}}, {row: 2, errs: map[string]string{"runtime": ""}}},
+		},
+		{
+			name: "empty body",
+			body: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, abortedAt := decodeRows(t, app.decodeNDJSONRows, tt.body)
+
+			if abortedAt != -1 {
+				t.Errorf("aborted at row %d; want no abort", abortedAt)
+			}
+
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestDecodeCSVRows(t *testing.T) {
+	app := newImportTestApplication()
+
+	tests := []struct {
+		name      string
+		body      string
+		want      []decodedRow
+		abortedAt int
+	}{
+		{
+			name:      "rows",
+			body:      "title,year,runtime,genres\nCasablanca,1942,102 mins,\"drama, romance\"\nUp,,96,\n",
+			want:      []decodedRow{{row: 1, input: casablanca}, {row: 2, input: &importRow{Title: "Up", Runtime: 96}}},
+			abortedAt: -1,
+		},
+		{
+			name:      "quoted fields",
+			body:      "genres,title,runtime,year\n\"drama,romance\",\"Casablanca\",\"1h 42m\",1942\nanimation,\"Up, \"\"the movie\"\"\",96,2009\n",
+			want:      []decodedRow{{row: 1, input: casablanca}, {row: 2, input: &importRow{Title: `Up, "the movie"`, Year: 2009, Runtime: 96, Genres: []string{"animation"}}}},
+			abortedAt: -1,
+		},
+		{
+			name:      "header case and spaces",
+			body:      " Title , YEAR,Runtime,genres\nUp,2009,96,animation\n",
+			want:      []decodedRow{{row: 1, input: &importRow{Title: "Up", Year: 2009, Runtime: 96, Genres: []string{"animation"}}}},
+			abortedAt: -1,
+		},
+		{
+			name: "rejected rows",
+			body: "title,year,runtime,genres\nUp,2009\nUp,soon,later,animation\nHeat,1995,170,crime\n",
+			want: []decodedRow{
+				{row: 1, errs: map[string]string{"body": ""}},
+				{row: 2, errs: map[string]string{"year": "", "runtime": ""}},
+				{row: 3, input: &importRow{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}}},
+			},
+			abortedAt: -1,
+		},
+		{
+			name:      "unknown column",
+			body:      "title,year,runtime,genres,rating\nUp,2009,96,animation,5\n",
+			abortedAt: 0,
+		},
+		{
+			name:      "missing column",
+			body:      "title,year,runtime\nUp,2009,96\n",
+			abortedAt: 0,
+		},
+		{
+			name:      "unterminated quote",
+			body:      "title,year,runtime,genres\nUp,2009,96,animation\n\"Casablanca,1942,102,drama\n",
+			want:      []decodedRow{{row: 1, input: &importRow{Title: "Up", Year: 2009, Runtime: 96, Genres: []string{"animation"}}}},
+			abortedAt: 2,
+		},
+		{
+			name:      "header only",
+			body:      "title,year,runtime,genres\n",
+			abortedAt: -1,
+		},
+		{
+			name:      "empty body",
+			body:      "",
+			abortedAt: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, abortedAt := decodeRows(t, app.decodeCSVRows, tt.body)
+
+			if abortedAt != tt.abortedAt {
+				t.Errorf("aborted at row %d; want %d", abortedAt, tt.abortedAt)
+			}
+
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestParseCSVRecord(t *testing.T) {
+	columns := map[string]int{"title": 0, "year": 1, "runtime": 2, "genres": 3}
+
+	tests := []struct {
+		name    string
+		record  []string
+		want    *importRow
+		wantErr []string
+	}{
+		{
+			name:   "all fields",
+			record: []string{"Casablanca", "1942", "102", "drama,romance"},
+			want:   casablanca,
+		},
+		{
+			name:   "spaces around values",
+			record: []string{"Casablanca", " 1942 ", " PT1H42M ", " drama , romance "},
+			want:   casablanca,
+		},
+		{
+			name:   "title kept as is",
+			record: []string{"  Up  ", "", "", ""},
+			want:   &importRow{Title: "  Up  "},
+		},
+		{
+			name:    "invalid year",
+			record:  []string{"Up", "2009.5", "96", "animation"},
+			wantErr: []string{"year"},
+		},
+		{
+			name:    "year out of range",
+			record:  []string{"Up", "4294969296", "96", "animation"},
+			wantErr: []string{"year"},
+		},
+		{
+			name:    "invalid year and runtime",
+			record:  []string{"Up", "soon", "1.5h", "animation"},
+			wantErr: []string{"year", "runtime"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := parseCSVRecord(tt.record, columns)
+
+			if tt.wantErr != nil {
+				if got != nil || len(errs) != len(tt.wantErr) {
+					t.Fatalf("got %+v, %v; want errors for %v", got, errs, tt.wantErr)
+				}
+				for _, key := range tt.wantErr {
+					if _, ok := errs[key]; !ok {
+						t.Errorf("got errors %v; want an error for %q", errs, key)
+					}
+				}
+				return
+			}
+
+			if errs != nil {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
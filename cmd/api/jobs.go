@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// The purgeTrash() helper launches a background job which permanently removes, at every
+// trash-purge-interval, the movies deleted more than trash-retention ago. The job returns once
+// the stop channel is closed, so that the graceful shutdown waits for a purge in progress.
+func (app *application) purgeTrash(stop <-chan struct{}) {
+	if app.config.trash.retention <= 0 || app.config.trash.purgeInterval <= 0 {
+		return
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(app.config.trash.purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			purged, posters, err := app.models.Movies.Purge(time.Now().Add(-app.config.trash.retention))
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				continue
+			}
+
+			for _, poster := range posters {
+				app.deletePoster(poster)
+			}
+
+			if purged > 0 {
+				app.logger.PrintInfo("purged deleted movies", map[string]string{
+					"count": strconv.FormatInt(purged, 10),
+				})
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurgeTrashStops(t *testing.T) {
+	tests := []struct {
+		name          string
+		retention     time.Duration
+		purgeInterval time.Duration
+	}{
+		{name: "enabled", retention: 30 * 24 * time.Hour, purgeInterval: time.Hour},
+		{name: "no retention", retention: 0, purgeInterval: time.Hour},
+		{name: "no interval", retention: 30 * 24 * time.Hour, purgeInterval: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+			app.config.trash.retention = tt.retention
+			app.config.trash.purgeInterval = tt.purgeInterval
+
+			stop := make(chan struct{})
+			app.purgeTrash(stop)
+			close(stop)
+
+			done := make(chan struct{})
+			go func() {
+				app.wg.Wait()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("the purge job didn't return once stopped")
+			}
+		})
+	}
+}
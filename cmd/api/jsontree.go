@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The encodeJSON() helper encodes v as indented JSON, the default format of responses.
+func encodeJSON(v any) ([]byte, error) {
+	js, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	// append a new line at the end of json for terminal apps
+	return append(js, '\n'), nil
+}
+
+// jsonObject is a decoded JSON object which, unlike a map, keeps its members in order, so
+// that a response can be rewritten without reordering its fields.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value any
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(member.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(member.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// get returns the value of the member with the given key, if there is one.
+func (o jsonObject) get(key string) (any, bool) {
+	for _, member := range o {
+		if member.Key == key {
+			return member.Value, true
+		}
+	}
+	return nil, false
+}
+
+// The toJSONTree() helper converts a value into the tree of its JSON representation, made of
+// jsonObject, []any, string, json.Number, bool and nil values.
+func toJSONTree(v any) (any, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	return decodeJSONTree(dec)
+}
+
+func decodeJSONTree(dec *json.Decoder) (any, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := jsonObject{}
+
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeJSONTree(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			object = append(object, jsonMember{Key: key.(string), Value: value})
+		}
+
+		// Consume the closing delimiter.
+		_, err = dec.Token()
+		return object, err
+
+	case json.Delim('['):
+		array := []any{}
+
+		for dec.More() {
+			value, err := decodeJSONTree(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			array = append(array, value)
+		}
+
+		_, err = dec.Token()
+		return array, err
+
+	case json.Delim('}'), json.Delim(']'):
+		return nil, fmt.Errorf("unexpected delimiter %v", token)
+
+	default:
+		return token, nil
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+// lookupMovieHandler resolves the identifier of a movie in a third-party dataset, given as a
+// query string parameter named after its provider (e.g. ?imdb=tt0111161), to the movie.
+func (app *application) lookupMovieHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	var provider, value string
+
+	for p := range validator.ExternalIDRX {
+		if qs.Has(p) {
+			if provider != "" {
+				v.AddError("provider", "must only give one external identifier")
+				break
+			}
+			provider, value = p, qs.Get(p)
+		}
+	}
+
+	if provider == "" {
+		providers := make([]string, 0, len(validator.ExternalIDRX))
+		for p := range validator.ExternalIDRX {
+			providers = append(providers, p)
+		}
+		slices.Sort(providers)
+
+		v.AddError("provider", "must give an external identifier with one of: "+strings.Join(providers, ", "))
+	} else {
+		v.Check(validator.ExternalID(provider, value), provider, "must be a valid "+provider+" identifier")
+	}
+
+	if !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetByExternalID(provider, value)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeMovieResponse(w, r, http.StatusOK, envelope{"movie": newMovieView(movie, format)}, movie, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
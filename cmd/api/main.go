@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +19,7 @@ import (
 	"greenlight.hichammou/internal/data"
 	"greenlight.hichammou/internal/jsonlog"
 	"greenlight.hichammou/internal/mailer"
+	"greenlight.hichammou/internal/storage"
 )
 
 const version = "1.0.0"
@@ -48,6 +52,48 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+
+	importer struct {
+		maxBytes  int64
+		batchSize int
+	}
+
+	trash struct {
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+
+	etag struct {
+		requireIfMatch bool
+	}
+
+	poster struct {
+		dir             string
+		maxBytes        int64
+		maxPixels       int64
+		thumbnailWidths []int
+	}
+
+	batch struct {
+		maxIDs int
+	}
+
+	similar struct {
+		genresWeight  float64
+		yearWeight    float64
+		runtimeWeight float64
+	}
+
+	stats struct {
+		cacheTTL time.Duration
+	}
+
+	suggest struct {
+		limit        int
+		cacheTTL     time.Duration
+		limiterRps   float64
+		limiterBurst int
+	}
 }
 
 type application struct {
@@ -56,6 +102,10 @@ type application struct {
 	models data.Models
 	mailer mailer.Mailer
 	wg     sync.WaitGroup
+
+	suggestions *suggestCache
+	stats       *statsCache
+	posters     storage.Storage
 }
 
 func main() {
@@ -93,6 +143,52 @@ func main() {
 		return nil
 	})
 
+	// Read the bulk import settings. The import endpoint accepts much larger bodies than readRequest().
+	flag.Int64Var(&cfg.importer.maxBytes, "import-max-bytes", 64<<20, "Maximum size in bytes of a bulk import request body")
+	flag.IntVar(&cfg.importer.batchSize, "import-batch-size", 500, "Number of movies inserted per transaction during bulk imports")
+
+	// Read how long soft-deleted movies are kept before being permanently removed.
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted movies are kept in the trash (0 disables purging)")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often the trash is purged")
+
+	// Reject movie updates and deletes which aren't made conditional with an If-Match header.
+	flag.BoolVar(&cfg.etag.requireIfMatch, "etag-require-if-match", false, "Require an If-Match header on movie updates and deletes")
+
+	// Read the poster upload settings.
+	flag.StringVar(&cfg.poster.dir, "poster-dir", "./uploads/posters", "Directory where the uploaded posters are stored")
+	flag.Int64Var(&cfg.poster.maxBytes, "poster-max-bytes", 10<<20, "Maximum size in bytes of an uploaded poster")
+	flag.Int64Var(&cfg.poster.maxPixels, "poster-max-pixels", 25_000_000, "Maximum number of pixels (width times height) of an uploaded poster")
+
+	cfg.poster.thumbnailWidths = []int{160, 320, 640}
+	flag.Func("poster-thumbnail-widths", "Widths in pixels of the poster thumbnails (space separated, default \"160 320 640\")", func(s string) error {
+		cfg.poster.thumbnailWidths = nil
+		for _, field := range strings.Fields(s) {
+			width, err := strconv.Atoi(field)
+			if err != nil || width < 1 {
+				return fmt.Errorf("invalid thumbnail width %q", field)
+			}
+			cfg.poster.thumbnailWidths = append(cfg.poster.thumbnailWidths, width)
+		}
+		return nil
+	})
+
+	// Read the maximum number of movies fetched by a single batch request.
+	flag.IntVar(&cfg.batch.maxIDs, "batch-max-ids", 100, "Maximum number of movies fetched by a batch request")
+
+	// Read the weights of the criteria used to rank similar movies.
+	flag.Float64Var(&cfg.similar.genresWeight, "similar-genres-weight", 0.6, "Weight of the genre overlap when ranking similar movies")
+	flag.Float64Var(&cfg.similar.yearWeight, "similar-year-weight", 0.25, "Weight of the year proximity when ranking similar movies")
+	flag.Float64Var(&cfg.similar.runtimeWeight, "similar-runtime-weight", 0.15, "Weight of the runtime closeness when ranking similar movies")
+
+	// Read how long the catalog statistics are cached when the movies aren't written to.
+	flag.DurationVar(&cfg.stats.cacheTTL, "stats-cache-ttl", 10*time.Minute, "How long the catalog statistics are cached (0 disables caching)")
+
+	// Read the title autocomplete settings.
+	flag.IntVar(&cfg.suggest.limit, "suggest-limit", 10, "Maximum number of title suggestions returned")
+	flag.DurationVar(&cfg.suggest.cacheTTL, "suggest-cache-ttl", 30*time.Second, "How long title suggestions are cached (0 disables caching)")
+	flag.Float64Var(&cfg.suggest.limiterRps, "suggest-limiter-rps", 10, "Rate limiter maximum requests per second for title suggestions")
+	flag.IntVar(&cfg.suggest.limiterBurst, "suggest-limiter-burst", 20, "Rate limiter maximum burst for title suggestions")
+
 	displayVersion := flag.Bool("version", false, "Display the version and exit")
 
 	flag.Parse()
@@ -107,6 +203,11 @@ func main() {
 	// init a new structured logger
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 
+	err := validateSimilarWeights(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -114,6 +215,11 @@ func main() {
 
 	defer db.Close()
 
+	posters, err := storage.NewFileSystem(cfg.poster.dir)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	logger.PrintInfo("Database connection pool established", nil)
 
 	// Publish a new "version" variable in the expvar handler containing the application version number
@@ -134,12 +240,19 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	// The statistics are invalidated by the models, whichever code path writes to the movies.
+	stats := newStatsCache(cfg.stats.cacheTTL)
+
 	// declare an instance of the app struct
 	app := &application{
 		config: cfg,
 		logger: logger,
-		models: data.NewModels(db),
+		models: data.NewModels(db, stats.invalidate),
 		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+
+		suggestions: newSuggestCache(cfg.suggest.cacheTTL),
+		stats:       stats,
+		posters:     posters,
 	}
 
 	err = app.serve()
@@ -148,6 +261,34 @@ func main() {
 	}
 }
 
+// validateSimilarWeights checks the weights of the similar movies ranking, which must not be
+// negative and must not all be zero, as only their proportions matter.
+func validateSimilarWeights(cfg config) error {
+	weights := []struct {
+		flag  string
+		value float64
+	}{
+		{"similar-genres-weight", cfg.similar.genresWeight},
+		{"similar-year-weight", cfg.similar.yearWeight},
+		{"similar-runtime-weight", cfg.similar.runtimeWeight},
+	}
+
+	var total float64
+
+	for _, w := range weights {
+		if w.value < 0 || math.IsNaN(w.value) || math.IsInf(w.value, 0) {
+			return fmt.Errorf("invalid -%s value %v: must be a finite number greater than or equal to 0", w.flag, w.value)
+		}
+		total += w.value
+	}
+
+	if total <= 0 {
+		return errors.New("invalid similarity weights: -similar-genres-weight, -similar-year-weight and -similar-runtime-weight must not all be 0")
+	}
+
+	return nil
+}
+
 func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
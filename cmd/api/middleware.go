@@ -55,7 +55,10 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) rateLimit(next http.Handler) http.Handler {
+// The rateLimit() middleware limits each client to rps requests per second, with bursts of up
+// to burst requests. Every call creates its own set of limiters, so the routes wrapped in
+// separate calls have separate budgets.
+func (app *application) rateLimit(rps float64, burst int, next http.Handler) http.Handler {
 	// Define a client struct to hold the rate limiter and last seen time for each client.
 	type client struct {
 		limiter  *rate.Limiter
@@ -99,7 +102,7 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			if _, found := clients[ip]; !found {
 				// Create and add a new client struct to the map if it doesn't already exists.
 				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
+					limiter: rate.NewLimiter(rate.Limit(rps), burst),
 				}
 			}
 
@@ -222,11 +225,12 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 			for _, trustedOrigin := range app.config.cors.trustedOrigins {
 				if trustedOrigin == origin {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 					// For the preflight requests.
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match, Runtime-Format")
 
 						// Write the 200 OK status and return from the middleware
 						w.WriteHeader(http.StatusOK)
@@ -3,17 +3,36 @@ package main
 import (
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"slices"
+	"strings"
 
 	"greenlight.hichammou/internal/data"
 	"greenlight.hichammou/internal/validator"
 )
 
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	// Fetching movies by id is a batch get rather than a search.
+	if r.URL.Query().Has("ids") {
+		v := validator.New()
+
+		ids := app.readIDs(r.URL.Query(), "ids", v)
+
+		if !v.Valide() {
+			app.faildValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		app.writeMovieBatch(w, r, ids)
+		return
+	}
+
 	// A struct to hold the query parameters values
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieSearch
+		Fields []string
+		Facets []string
 		data.Filters
 	}
 
@@ -23,14 +42,35 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 	// Initialize a new Validator instance
 	v := validator.New()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	search, err := app.readMovieSearch(qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	input.MovieSearch = search
+	input.Fields = app.readCSV(qs, "fields", []string{})
+	input.Facets = app.readCSV(qs, "facets", []string{})
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "rating", "relevance", "-id", "-title", "-year", "-runtime", "-rating"}
+
+	v.Check(input.Filters.Sort != "relevance" || input.Title != "", "sort", "relevance can only be used with a title search")
+
+	// A "cursor" parameter (empty for the first page) switches the list to keyset pagination.
+	cursorMode := qs.Has("cursor")
+
+	cursor, err := data.DecodeCursor(qs.Get("cursor"))
+	if err != nil {
+		v.AddError("cursor", "must be a cursor returned by a previous request")
+	}
+
+	data.ValidateCursor(v, cursor, input.Filters)
+	data.ValidateFields(v, input.Fields, data.MovieListFieldsSafelist)
+	data.ValidateFacets(v, input.Facets)
 
 	// execute the validation checks on the filters struct and send a response containing the errors if there any
 	if data.ValidateFilters(v, input.Filters); !v.Valide() {
@@ -38,13 +78,63 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	movies, metadat, err := app.models.Movies.List(input.Title, input.Genres, input.Filters)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+	env := envelope{}
+	format := app.contextGetRuntimeFormat(r)
+
+	if cursorMode {
+		movies, metadata, err := app.models.Movies.ListAfter(input.MovieSearch, input.Fields, cursor, input.Filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		_, err = app.localizeTitles(w, r, movies...)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["movies"], err = sparseFieldsAll(newMovieViews(movies, format), input.Fields)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["metadata"] = metadata
+	} else {
+		movies, metadat, err := app.models.Movies.List(input.MovieSearch, input.Fields, input.Filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		_, err = app.localizeTitles(w, r, movies...)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["movies"], err = sparseFieldsAll(newMovieViews(movies, format), input.Fields)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["metadata"] = metadat
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadat}, nil)
+	// Facets are opt-in, as each one costs an extra query.
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Movies.Facets(input.MovieSearch, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["facets"] = facets
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -53,14 +143,15 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 	// Declare a new anonymos struct to hold the information that we expect to be in the HTTP request body
 	var input struct {
-		Title   string       `json:"title"`
-		Year    int32        `json:"year"`
-		Runtime data.Runtime `json:"runtime"`
-		Genres  []string     `json:"genres"`
+		Title       string           `json:"title"`
+		Year        int32            `json:"year"`
+		Runtime     data.Runtime     `json:"runtime"`
+		Genres      []string         `json:"genres"`
+		ExternalIDs data.ExternalIDs `json:"external_ids"`
 	}
 
 	//Initialize a new json.Decoder instance which reads from the request body and then use Decode() to decode the body contents into input struct
-	err := app.readJSON(w, r, &input)
+	err := app.readRequest(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -70,28 +161,43 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	v := validator.New()
 
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:       input.Title,
+		Year:        input.Year,
+		Runtime:     input.Runtime,
+		Genres:      input.Genres,
+		ExternalIDs: input.ExternalIDs,
+	}
+
+	genres, err := app.models.Genres.Taxonomy()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	// Check if the fields passed the check
-	if data.ValidateMovie(v, movie); !v.Valide() {
+	if data.ValidateMovie(v, movie, genres); !v.Valide() {
 		app.faildValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Insert(movie)
+	err = app.models.Movies.Insert(movie, app.contextGetUser(r).ID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "an identifier already belongs to another movie")
+			app.faildValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"movie": movie}, headers)
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeMovieResponse(w, r, http.StatusCreated, envelope{"movie": newMovieView(movie, format)}, movie, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -114,52 +220,99 @@ func (app *application) updateMovieHanler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var inputs struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
-	}
-
-	err = app.readJSON(w, r, &inputs)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
+	if !app.checkIfMatch(w, r, movie) {
 		return
 	}
 
-	// zero value of pointers is nil, so we can you that to do partial updates
-	if inputs.Title != nil {
-		movie.Title = *inputs.Title
-	}
-	if inputs.Year != nil {
-		movie.Year = *inputs.Year
-	}
-	if inputs.Runtime != nil {
-		movie.Runtime = *inputs.Runtime
+	w.Header().Set("Accept-Patch", strings.Join(patchMediaTypes, ", "))
+
+	// Besides the partial updates read by readRequest(), the movie can be patched with a JSON
+	// Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), chosen with the Content-Type of the
+	// request.
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch {
+	case slices.Contains(patchMediaTypes, mediaType):
+		err = app.applyMoviePatch(w, r, mediaType, movie)
+		if err != nil {
+			switch {
+			case errors.Is(err, errPatchConflict):
+				app.faildValidationResponse(w, r, map[string]string{"patch": err.Error()})
+			default:
+				app.badRequestResponse(w, r, err)
+			}
+			return
+		}
+
+	default:
+		var inputs struct {
+			Title       *string          `json:"title"`
+			Year        *int32           `json:"year"`
+			Runtime     *data.Runtime    `json:"runtime"`
+			Genres      []string         `json:"genres"`
+			ExternalIDs data.ExternalIDs `json:"external_ids"`
+		}
+
+		err = app.readRequest(w, r, &inputs)
+		if err != nil {
+			var unsupportedMediaType *unsupportedMediaTypeError
+			switch {
+			case errors.As(err, &unsupportedMediaType):
+				app.unsupportedMediaTypeResponse(w, r, append(unsupportedMediaType.supported, patchMediaTypes...)...)
+			default:
+				app.badRequestResponse(w, r, err)
+			}
+			return
+		}
+
+		// zero value of pointers is nil, so we can you that to do partial updates
+		if inputs.Title != nil {
+			movie.Title = *inputs.Title
+		}
+		if inputs.Year != nil {
+			movie.Year = *inputs.Year
+		}
+		if inputs.Runtime != nil {
+			movie.Runtime = *inputs.Runtime
+		}
+		if inputs.Genres != nil {
+			movie.Genres = inputs.Genres
+		}
+		if inputs.ExternalIDs != nil {
+			movie.ExternalIDs = inputs.ExternalIDs
+		}
 	}
-	if inputs.Genres != nil {
-		movie.Genres = inputs.Genres
+
+	genres, err := app.models.Genres.Taxonomy()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	v := validator.New()
 
-	if data.ValidateMovie(v, movie); !v.Valide() {
+	if data.ValidateMovie(v, movie, genres); !v.Valide() {
 		app.faildValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Update(movie)
+	err = app.models.Movies.Update(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			v.AddError("external_ids", "an identifier already belongs to another movie")
+			app.faildValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeMovieResponse(w, r, http.StatusOK, envelope{"movie": newMovieView(movie, format)}, movie, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -183,17 +336,100 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Delete(id)
+	if !app.checkIfMatch(w, r, movie) {
+		return
+	}
+
+	// The movie is only deleted if it's still at the version checked against If-Match.
+	err = app.models.Movies.Delete(id, movie.Version, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movie": newMovieView(movie, format)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+
+}
+
+func (app *application) listDeletedMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.MovieSearch
+		data.Filters
+	}
+
+	qs := r.URL.Query()
+
+	v := validator.New()
+
+	search, err := app.readMovieSearch(qs, v)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	input.MovieSearch = search
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "deleted_at", "-id", "-title", "-year", "-runtime", "-deleted_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.ListDeleted(input.MovieSearch, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"movies": newMovieViews(movies, format), "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Restore(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
 	}
 
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeMovieResponse(w, r, http.StatusOK, envelope{"movie": newMovieView(movie, format)}, movie, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *application) ShowMovieHandler(w http.ResponseWriter, r *http.Request) {
@@ -203,7 +439,22 @@ func (app *application) ShowMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	v := validator.New()
+
+	// The "include" parameter lists the related resources to embed in the movie.
+	include := app.readCSV(r.URL.Query(), "include", []string{})
+	for _, value := range include {
+		v.Check(validator.In(value, "credits"), "include", "invalid include value")
+	}
+
+	fields := app.readCSV(r.URL.Query(), "fields", []string{})
+
+	if data.ValidateFields(v, fields, data.MovieFieldsSafelist); !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(id, fields)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -214,7 +465,43 @@ func (app *application) ShowMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if validator.In("credits", include...) {
+		movie.Credits, err = app.models.Credits.GetAllForMovie(movie.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// The collection is part of the default fields, but only loaded with a fieldset asking for it.
+	if len(fields) == 0 || validator.In("collection", fields...) {
+		movie.Collection, err = app.models.Collections.GetForMovie(movie.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	_, err = app.localizeTitles(w, r, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var body any = newMovieView(movie, app.contextGetRuntimeFormat(r))
+
+	if len(fields) > 0 {
+		// The included resources are kept along with the requested fields.
+		body, err = sparseFields(body, append(fields, include...))
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// Credits, collections and localized titles are edited without changing the movie version,
+	// so the ETag is derived from the representation actually sent.
+	err = app.writeMovieResponse(w, r, http.StatusOK, envelope{"movie": body}, movie, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// The encodeMsgpackBody() helper encodes the JSON representation of v as MessagePack.
+func encodeMsgpackBody(v any) ([]byte, error) {
+	tree, err := toJSONTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = encodeMsgpack(msgpack.NewEncoder(&buf), tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// The encodeMsgpack() helper writes the MessagePack encoding of a JSON tree, as returned by
+// toJSONTree(), keeping the members of objects in order.
+func encodeMsgpack(enc *msgpack.Encoder, tree any) error {
+	switch node := tree.(type) {
+	case json.Number:
+		if i, err := node.Int64(); err == nil {
+			return enc.EncodeInt(i)
+		}
+
+		f, err := node.Float64()
+		if err != nil {
+			return err
+		}
+
+		return enc.EncodeFloat64(f)
+
+	case []any:
+		err := enc.EncodeArrayLen(len(node))
+		if err != nil {
+			return err
+		}
+
+		for _, value := range node {
+			err = encodeMsgpack(enc, value)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case jsonObject:
+		err := enc.EncodeMapLen(len(node))
+		if err != nil {
+			return err
+		}
+
+		for _, member := range node {
+			err = enc.EncodeString(member.Key)
+			if err != nil {
+				return err
+			}
+
+			err = encodeMsgpack(enc, member.Value)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	default:
+		return enc.Encode(node)
+	}
+}
+
+// The decodeMsgpack() helper decodes a single MessagePack value into the types used by
+// encoding/json for an any value: map[string]any, []any, string, bool and nil, with int64,
+// uint64 and float64 numbers so that integers don't lose precision. Binary data is decoded as
+// a string. Errors are client friendly messages.
+func decodeMsgpack(body []byte) (any, error) {
+	r := bytes.NewReader(body)
+
+	value, err := decodeMsgpackValue(msgpack.NewDecoder(r), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Len() > 0 {
+		return nil, errors.New("body must contain only one single MessagePack value")
+	}
+
+	return value, nil
+}
+
+// msgpackMaxDepth bounds the nesting of arrays and maps, so that a malicious body can't
+// exhaust the stack.
+const msgpackMaxDepth = 100
+
+// badMsgpackError translates an error of the msgpack decoder into a client friendly message.
+func badMsgpackError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errors.New("body contains badly-formed MessagePack: unexpected end of data")
+	}
+
+	return fmt.Errorf("body contains badly-formed MessagePack: %v", err)
+}
+
+// decodeMsgpackValue decodes arrays and maps itself, to bound their nesting and to only accept
+// string keys, and leaves the other values to the msgpack decoder.
+func decodeMsgpackValue(dec *msgpack.Decoder, depth int) (any, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errors.New("body contains MessagePack data nested too deeply")
+	}
+
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, badMsgpackError(err)
+	}
+
+	switch {
+	case msgpcode.IsFixedArray(code), code == msgpcode.Array16, code == msgpcode.Array32:
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, badMsgpackError(err)
+		}
+
+		// The array isn't allocated upfront, as its length is given by the client.
+		array := []any{}
+
+		for range n {
+			value, err := decodeMsgpackValue(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+
+		return array, nil
+
+	case msgpcode.IsFixedMap(code), code == msgpcode.Map16, code == msgpcode.Map32:
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, badMsgpackError(err)
+		}
+
+		object := map[string]any{}
+
+		for range n {
+			code, err := dec.PeekCode()
+			if err != nil {
+				return nil, badMsgpackError(err)
+			}
+
+			if !msgpcode.IsString(code) {
+				return nil, errors.New("body contains a MessagePack map key which isn't a string")
+			}
+
+			key, err := dec.DecodeString()
+			if err != nil {
+				return nil, badMsgpackError(err)
+			}
+
+			object[key], err = decodeMsgpackValue(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return object, nil
+	}
+
+	value, err := dec.DecodeInterfaceLoose()
+	if err != nil {
+		return nil, badMsgpackError(err)
+	}
+
+	switch value := value.(type) {
+	case nil, bool, string, int64, uint64:
+		return value, nil
+
+	case float64:
+		// JSON has no representation for them, and no field accepts them.
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, errors.New("body must not contain NaN or infinite numbers")
+		}
+		return value, nil
+
+	default:
+		return nil, fmt.Errorf("body contains an unsupported MessagePack value of type %T", value)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// mustMarshalMsgpack returns the MessagePack encoding of v, made by the msgpack library itself.
+func mustMarshalMsgpack(t *testing.T, v any) []byte {
+	t.Helper()
+
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    []byte
+		want    any
+		wantErr string
+	}{
+		{
+			name: "object",
+			body: mustMarshalMsgpack(t, map[string]any{"title": "Casablanca", "year": 1942, "runtime": "102 mins", "genres": []string{"drama", "romance"}}),
+			want: map[string]any{"title": "Casablanca", "year": uint64(1942), "runtime": "102 mins", "genres": []any{"drama", "romance"}},
+		},
+		{
+			name: "scalars",
+			body: mustMarshalMsgpack(t, []any{nil, true, -1, uint64(math.MaxUint64), 1.5, float32(0.25)}),
+			want: []any{nil, true, int64(-1), uint64(math.MaxUint64), 1.5, 0.25},
+		},
+		{
+			name: "binary as string",
+			body: mustMarshalMsgpack(t, []byte("drama")),
+			want: "drama",
+		},
+		{
+			name: "empty collections",
+			body: []byte{0x92, 0x90, 0x80},
+			want: []any{[]any{}, map[string]any{}},
+		},
+		{
+			name:    "NaN",
+			body:    mustMarshalMsgpack(t, map[string]any{"rating": math.NaN()}),
+			wantErr: "body must not contain NaN or infinite numbers",
+		},
+		{
+			name:    "infinity",
+			body:    mustMarshalMsgpack(t, []float32{float32(math.Inf(-1))}),
+			wantErr: "body must not contain NaN or infinite numbers",
+		},
+		{
+			name:    "integer key",
+			body:    mustMarshalMsgpack(t, map[int]string{1: "drama"}),
+			wantErr: "body contains a MessagePack map key which isn't a string",
+		},
+		{
+			name:    "truncated string",
+			body:    []byte{0xa5, 'd', 'r'},
+			wantErr: "body contains badly-formed MessagePack: unexpected end of data",
+		},
+		{
+			name:    "truncated map",
+			body:    []byte{0x82, 0xa1, 'a', 0x01},
+			wantErr: "body contains badly-formed MessagePack: unexpected end of data",
+		},
+		{
+			name:    "empty",
+			body:    []byte{},
+			wantErr: "body contains badly-formed MessagePack: unexpected end of data",
+		},
+		{
+			name:    "unused code",
+			body:    []byte{0xc1},
+			wantErr: "body contains badly-formed MessagePack",
+		},
+		{
+			name:    "trailing value",
+			body:    []byte{0x01, 0x02},
+			wantErr: "body must contain only one single MessagePack value",
+		},
+		{
+			name:    "nested too deeply",
+			body:    append(bytes.Repeat([]byte{0x91}, msgpackMaxDepth+1), 0xc0),
+			wantErr: "body contains MessagePack data nested too deeply",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeMsgpack(tt.body)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v; want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeMsgpackBody(t *testing.T) {
+	body, err := encodeMsgpackBody(envelope{"movie": map[string]any{"id": 1, "title": "Casablanca", "rating": 4.5, "genres": []string{"drama"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decodeMsgpack(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"movie": map[string]any{"id": int64(1), "title": "Casablanca", "rating": 4.5, "genres": []any{"drama"}}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v; want %#v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/jsonpatch"
+)
+
+// patchMediaTypes lists the patch formats accepted by PATCH /v1/movies/:id, besides the plain
+// JSON partial updates.
+var patchMediaTypes = []string{"application/merge-patch+json", "application/json-patch+json"}
+
+// movieDocument is the part of a movie which patches are applied to. Every member is always
+// present, so that JSON Patch operations can replace or test them.
+type movieDocument struct {
+	Title       string           `json:"title"`
+	Year        int32            `json:"year"`
+	Runtime     data.Runtime     `json:"runtime"`
+	Genres      []string         `json:"genres"`
+	ExternalIDs data.ExternalIDs `json:"external_ids"`
+}
+
+// errPatchConflict is wrapped by the errors returned by applyMoviePatch() for well-formed
+// patches which can't be applied to the movie, like a failed "test" operation.
+var errPatchConflict = errors.New("patch conflict")
+
+// The applyMoviePatch() helper applies the patch in the request body, in the format given by
+// its media type, to the editable fields of the movie. Members removed by the patch, or set to
+// null, are left to their zero value for ValidateMovie() to report them.
+func (app *application) applyMoviePatch(w http.ResponseWriter, r *http.Request, mediaType string, movie *data.Movie) error {
+	maxBytes := int64(1_048_576)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		return decodeJSONError(err, maxBytes)
+	}
+
+	if !json.Valid(patch) {
+		return errors.New("body contains badly-formed JSON")
+	}
+
+	doc, err := json.Marshal(movieDocument{
+		Title:       movie.Title,
+		Year:        movie.Year,
+		Runtime:     movie.Runtime,
+		Genres:      movie.Genres,
+		ExternalIDs: movie.ExternalIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case "application/merge-patch+json":
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	default:
+		doc, err = jsonpatch.Apply(doc, patch)
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrInvalidPatch) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errPatchConflict, err)
+	}
+
+	// The patched document is decoded as strictly as a request body, so that patches can't add
+	// members which aren't editable.
+	var patched movieDocument
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(&patched)
+	if err != nil {
+		return fmt.Errorf("patched movie: %w", decodeJSONError(err, maxBytes))
+	}
+
+	movie.Title = patched.Title
+	movie.Year = patched.Year
+	movie.Runtime = patched.Runtime
+	movie.Genres = patched.Genres
+
+	// Unlike the other fields, removing the external identifiers clears them.
+	movie.ExternalIDs = patched.ExternalIDs
+	if movie.ExternalIDs == nil {
+		movie.ExternalIDs = data.ExternalIDs{}
+	}
+
+	return nil
+}
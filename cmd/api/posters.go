@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/image/draw"
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/storage"
+	"greenlight.hichammou/internal/validator"
+)
+
+// posterTypes maps the sniffed content types accepted for posters to the extension the
+// originals are stored with.
+var posterTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// thumbnailKey returns the storage key of the thumbnail of a poster at the given width.
+// Thumbnails are always encoded as JPEG.
+func thumbnailKey(key string, width int) string {
+	return fmt.Sprintf("%s-w%d.jpg", strings.TrimSuffix(key, path.Ext(key)), width)
+}
+
+// multipartError translates an error met while reading a multipart body into a client
+// friendly message.
+func multipartError(err error) error {
+	var maxBytesError *http.MaxBytesError
+
+	if errors.As(err, &maxBytesError) {
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+	}
+
+	return fmt.Errorf("body contains a badly-formed multipart form: %w", err)
+}
+
+// checkPosterPixels checks that a poster is a valid image of at most maxPixels pixels. Only the
+// header is decoded, so that the size is checked before the pixels are allocated: a small
+// file can declare dimensions taking gigabytes of memory once decoded.
+func checkPosterPixels(v *validator.Validator, content []byte, maxPixels int64) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		v.AddError("poster", "must be a valid JPEG or PNG image")
+		return
+	}
+
+	pixels := int64(config.Width) * int64(config.Height)
+	v.Check(pixels <= maxPixels, "poster", fmt.Sprintf("must not have more than %d pixels, has %dx%d", maxPixels, config.Width, config.Height))
+}
+
+// uploadPosterHandler replaces the poster of a movie with the image sent in the "poster" field
+// of a multipart/form-data body. The original is stored as is, and the thumbnails are
+// generated in the background.
+func (app *application) uploadPosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Leave some room for the multipart boundaries and the other fields, the size of the
+	// image itself is checked below.
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.poster.maxBytes+64*1024)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r, "multipart/form-data")
+		return
+	}
+
+	var content []byte
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			app.badRequestResponse(w, r, multipartError(err))
+			return
+		}
+
+		if part.FormName() != "poster" {
+			continue
+		}
+
+		content, err = io.ReadAll(io.LimitReader(part, app.config.poster.maxBytes+1))
+		if err != nil {
+			app.badRequestResponse(w, r, multipartError(err))
+			return
+		}
+		break
+	}
+
+	v := validator.New()
+
+	v.Check(content != nil, "poster", "must be provided")
+	v.Check(int64(len(content)) <= app.config.poster.maxBytes, "poster", fmt.Sprintf("must not be larger than %d bytes", app.config.poster.maxBytes))
+
+	// Trust the content of the file rather than the Content-Type sent by the client.
+	ext, ok := posterTypes[http.DetectContentType(content)]
+	v.Check(ok, "poster", "must be a JPEG or PNG image")
+
+	if !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if checkPosterPixels(v, content, app.config.poster.maxPixels); !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Every upload gets a new key, so that the URL of a poster never changes its content.
+	token := make([]byte, 16)
+	_, err = rand.Read(token)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := fmt.Sprintf("%d/%s%s", id, hex.EncodeToString(token), ext)
+
+	err = app.posters.Put(key, bytes.NewReader(content))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	previous, err := app.models.Movies.SetPoster(id, key)
+	if err != nil {
+		app.deletePoster(key)
+
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		app.generateThumbnails(key, content)
+
+		if previous != "" {
+			app.deletePoster(previous)
+		}
+	})
+
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	format := app.contextGetRuntimeFormat(r)
+
+	err = app.writeMovieResponse(w, r, http.StatusOK, envelope{"movie": newMovieView(movie, format)}, movie, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The generateThumbnails() helper stores a downscaled JPEG copy of a poster for each of the
+// configured widths narrower than the original. Errors are logged, as it runs in the background.
+func (app *application) generateThumbnails(key string, content []byte) {
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"poster": key})
+		return
+	}
+
+	bounds := img.Bounds()
+
+	for _, width := range app.config.poster.thumbnailWidths {
+		if width >= bounds.Dx() {
+			continue
+		}
+
+		height := max(1, bounds.Dy()*width/bounds.Dx())
+		thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+
+		// JPEG has no transparency, so draw the transparent areas of PNGs over white.
+		draw.Draw(thumbnail, thumbnail.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Over, nil)
+
+		var buf bytes.Buffer
+
+		err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85})
+		if err == nil {
+			err = app.posters.Put(thumbnailKey(key, width), &buf)
+		}
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"poster": key, "width": fmt.Sprint(width)})
+		}
+	}
+}
+
+// The deletePoster() helper removes a poster and its thumbnails from the storage, logging
+// any error.
+func (app *application) deletePoster(key string) {
+	keys := []string{key}
+	for _, width := range app.config.poster.thumbnailWidths {
+		keys = append(keys, thumbnailKey(key, width))
+	}
+
+	for _, key := range keys {
+		err := app.posters.Delete(key)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"poster": key})
+		}
+	}
+}
+
+// showPosterHandler serves a poster, or one of its thumbnails with the "width" parameter. The
+// route doesn't require any permission, so that posters can be used directly in <img> tags.
+func (app *application) showPosterHandler(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(httprouter.ParamsFromContext(r.Context()).ByName("key"), "/")
+
+	v := validator.New()
+
+	width := app.readInt(r.URL.Query(), "width", 0, v)
+	v.Check(width == 0 || validator.In(width, app.config.poster.thumbnailWidths...), "width", "must be one of the available thumbnail widths")
+
+	if !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// A poster is never modified once uploaded, so it can be cached forever.
+	cacheControl := "public, max-age=31536000, immutable"
+
+	name := key
+	if width != 0 {
+		name = thumbnailKey(key, width)
+	}
+
+	file, modTime, err := app.posters.Get(name)
+	if errors.Is(err, storage.ErrNotFound) && width != 0 {
+		// The thumbnail may not be generated yet, or the original may be too narrow to need
+		// one. Serve the original instead, but only let it be cached briefly.
+		name = key
+		cacheControl = "public, max-age=60"
+
+		file, modTime, err = app.posters.Get(name)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidKey):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent sets the Content-Type from the extension of the name, and handles the
+	// conditional and range requests.
+	http.ServeContent(w, r, path.Base(name), modTime, file)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestThumbnailKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		width int
+		want  string
+	}{
+		{key: "7/0a1b.png", width: 160, want: "7/0a1b-w160.jpg"},
+		{key: "7/0a1b.jpg", width: 640, want: "7/0a1b-w640.jpg"},
+		{key: "7/0a1b", width: 320, want: "7/0a1b-w320.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := thumbnailKey(tt.key, tt.width); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPosterPixels(t *testing.T) {
+	encode := func(t *testing.T, width, height int, format string) []byte {
+		t.Helper()
+
+		var buf bytes.Buffer
+		img := image.NewGray(image.Rect(0, 0, width, height))
+
+		var err error
+		if format == "png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name      string
+		content   func(t *testing.T) []byte
+		maxPixels int64
+		valid     bool
+	}{
+		{name: "png within budget", content: func(t *testing.T) []byte { return encode(t, 40, 60, "png") }, maxPixels: 2400, valid: true},
+		{name: "jpeg within budget", content: func(t *testing.T) []byte { return encode(t, 30, 20, "jpeg") }, maxPixels: 2400, valid: true},
+		{name: "png over budget", content: func(t *testing.T) []byte { return encode(t, 40, 61, "png") }, maxPixels: 2400, valid: false},
+		{name: "long and thin", content: func(t *testing.T) []byte { return encode(t, 2401, 1, "png") }, maxPixels: 2400, valid: false},
+		{name: "truncated header", content: func(t *testing.T) []byte { return encode(t, 40, 60, "png")[:20] }, maxPixels: 2400, valid: false},
+		{name: "not an image", content: func(t *testing.T) []byte { return []byte("GIF89a") }, maxPixels: 2400, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			checkPosterPixels(v, tt.content(t), tt.maxPixels)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestShowMovieRevisionHandlerCompareTo(t *testing.T) {
+	app := &application{}
+
+	tests := []struct {
+		compareTo string
+		want      string
+	}{
+		{compareTo: "0", want: "must be a positive integer"},
+		{compareTo: "-3", want: "must be a positive integer"},
+		{compareTo: "", want: "must be a positive integer"},
+		{compareTo: "two", want: "must be an integer value"},
+		{compareTo: "4294967298", want: "must be between -2147483648 and 2147483647"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.compareTo, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/movies/1/revisions/2?compare_to="+tt.compareTo, nil)
+
+			params := httprouter.Params{{Key: "id", Value: "1"}, {Key: "version", Value: "2"}}
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, params))
+
+			app.showMovieRevisionHandler(rr, r)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+
+			var body struct {
+				Error map[string]string `json:"error"`
+			}
+
+			err := json.Unmarshal(rr.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := body.Error["compare_to"]; got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
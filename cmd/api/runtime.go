@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+const runtimeFormatContextKey = contextKey("runtimeFormat")
+
+// The runtimeFormat() middleware reads the representation of runtimes chosen by the client,
+// with the runtime_format query string parameter or else the Runtime-Format header, and
+// stores it in the request context for the handlers building the response views. An unknown
+// format is rejected before the request is handled.
+func (app *application) runtimeFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Runtime-Format")
+
+		format := r.URL.Query().Get("runtime_format")
+		if format == "" {
+			format = strings.TrimSpace(r.Header.Get("Runtime-Format"))
+		}
+
+		if format != "" {
+			v := validator.New()
+
+			v.Check(validator.In(format, data.RuntimeFormats...), "runtime_format", "must be one of: "+strings.Join(data.RuntimeFormats, ", "))
+
+			if !v.Valide() {
+				app.faildValidationResponse(w, r, v.Errors)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), runtimeFormatContextKey, format))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) contextGetRuntimeFormat(r *http.Request) string {
+	format, ok := r.Context().Value(runtimeFormatContextKey).(string)
+	if !ok {
+		return data.RuntimeFormatMins
+	}
+
+	return format
+}
+
+// The types below are the views of the values holding runtimes, as sent in responses. Their
+// Runtime fields are less nested than the ones of the embedded values, so they replace them
+// when marshaled. Handlers build them with the format returned by contextGetRuntimeFormat(),
+// right before sending the response.
+
+type movieView struct {
+	*data.Movie
+	Runtime any `json:"runtime,omitempty"`
+}
+
+// newMovieView returns the view of a movie with its runtime in the given format, or nil if the
+// movie is nil.
+func newMovieView(movie *data.Movie, format string) *movieView {
+	if movie == nil {
+		return nil
+	}
+
+	view := &movieView{Movie: movie}
+	if movie.Runtime != 0 {
+		view.Runtime = movie.Runtime.Format(format)
+	}
+
+	return view
+}
+
+// newMovieViews returns the views of movies, keeping a nil slice nil so that it's still omitted
+// from the responses.
+func newMovieViews(movies []*data.Movie, format string) []*movieView {
+	if movies == nil {
+		return nil
+	}
+
+	views := make([]*movieView, len(movies))
+	for i, movie := range movies {
+		views[i] = newMovieView(movie, format)
+	}
+
+	return views
+}
+
+type collectionView struct {
+	*data.Collection
+	Movies []*movieView `json:"movies,omitempty"`
+}
+
+func newCollectionView(collection *data.Collection, format string) *collectionView {
+	return &collectionView{Collection: collection, Movies: newMovieViews(collection.Movies, format)}
+}
+
+type watchlistEntryView struct {
+	*data.WatchlistEntry
+	Movie *movieView `json:"movie"`
+}
+
+func newWatchlistEntryView(entry *data.WatchlistEntry, format string) *watchlistEntryView {
+	return &watchlistEntryView{WatchlistEntry: entry, Movie: newMovieView(entry.Movie, format)}
+}
+
+func newWatchlistEntryViews(entries []*data.WatchlistEntry, format string) []*watchlistEntryView {
+	views := make([]*watchlistEntryView, len(entries))
+	for i, entry := range entries {
+		views[i] = newWatchlistEntryView(entry, format)
+	}
+
+	return views
+}
+
+type movieSnapshotView struct {
+	*data.MovieSnapshot
+	Runtime any `json:"runtime"`
+}
+
+// newMovieSnapshotView returns the view of a snapshot, or nil if the snapshot is nil, as for
+// the state before the insert revision of a movie.
+func newMovieSnapshotView(snapshot *data.MovieSnapshot, format string) *movieSnapshotView {
+	if snapshot == nil {
+		return nil
+	}
+
+	return &movieSnapshotView{MovieSnapshot: snapshot, Runtime: snapshot.Runtime.Format(format)}
+}
+
+type movieRevisionView struct {
+	*data.MovieRevision
+	Before *movieSnapshotView `json:"before"`
+	After  *movieSnapshotView `json:"after"`
+}
+
+func newMovieRevisionView(revision *data.MovieRevision, format string) *movieRevisionView {
+	return &movieRevisionView{
+		MovieRevision: revision,
+		Before:        newMovieSnapshotView(revision.Before, format),
+		After:         newMovieSnapshotView(revision.After, format),
+	}
+}
+
+func newMovieRevisionViews(revisions []*data.MovieRevision, format string) []*movieRevisionView {
+	views := make([]*movieRevisionView, len(revisions))
+	for i, revision := range revisions {
+		views[i] = newMovieRevisionView(revision, format)
+	}
+
+	return views
+}
+
+// formatFieldChanges returns a copy of changes with the values of the runtime in the given
+// format.
+func formatFieldChanges(changes []data.FieldChange, format string) []data.FieldChange {
+	formatted := make([]data.FieldChange, len(changes))
+
+	for i, change := range changes {
+		if from, ok := change.From.(data.Runtime); ok {
+			change.From = from.Format(format)
+		}
+		if to, ok := change.To.(data.Runtime); ok {
+			change.To = to.Format(format)
+		}
+		formatted[i] = change
+	}
+
+	return formatted
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"greenlight.hichammou/internal/data"
+)
+
+func TestRuntimeViews(t *testing.T) {
+	casablanca := &data.Movie{ID: 1, Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama"}, Version: 1}
+	snapshot := &data.MovieSnapshot{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama"}}
+
+	tests := []struct {
+		name string
+		view any
+		want string
+	}{
+		{
+			name: "movie in minutes",
+			view: newMovieView(casablanca, data.RuntimeFormatMins),
+			want: `{"id":1,"title":"Casablanca","year":1942,"genres":["drama"],"version":1,"rating":{"average":0,"count":0},"runtime":"102 mins"}`,
+		},
+		{
+			name: "movie in hours and minutes",
+			view: newMovieView(casablanca, data.RuntimeFormatHM),
+			want: `{"id":1,"title":"Casablanca","year":1942,"genres":["drama"],"version":1,"rating":{"average":0,"count":0},"runtime":"1h 42m"}`,
+		},
+		{
+			name: "movie without runtime",
+			view: newMovieView(&data.Movie{ID: 2, Title: "Up"}, data.RuntimeFormatInteger),
+			want: `{"id":2,"title":"Up","version":0,"rating":{"average":0,"count":0}}`,
+		},
+		{
+			name: "collection",
+			view: newCollectionView(&data.Collection{ID: 3, Name: "Classics", MovieIDs: []int64{1}, Movies: []*data.Movie{casablanca}}, data.RuntimeFormatInteger),
+			want: `{"id":3,"name":"Classics","movie_ids":[1],"version":0,"movies":[{"id":1,"title":"Casablanca","year":1942,"genres":["drama"],"version":1,"rating":{"average":0,"count":0},"runtime":102}]}`,
+		},
+		{
+			name: "collection without movies",
+			view: newCollectionView(&data.Collection{ID: 3, Name: "Classics", MovieIDs: []int64{}}, data.RuntimeFormatHM),
+			want: `{"id":3,"name":"Classics","movie_ids":[],"version":0}`,
+		},
+		{
+			name: "insert revision",
+			view: newMovieRevisionView(&data.MovieRevision{MovieID: 1, Version: 1, Action: "insert", After: snapshot}, data.RuntimeFormatISO8601),
+			want: `{"movie_id":1,"version":1,"action":"insert","user_id":null,"created_at":"0001-01-01T00:00:00Z","before":null,"after":{"title":"Casablanca","year":1942,"genres":["drama"],"runtime":"PT1H42M"}}`,
+		},
+		{
+			name: "field changes",
+			view: formatFieldChanges([]data.FieldChange{{Field: "year", From: int32(1941), To: int32(1942)}, {Field: "runtime", From: data.Runtime(90), To: data.Runtime(102)}}, data.RuntimeFormatHM),
+			want: `[{"field":"year","from":1941,"to":1942},{"field":"runtime","from":"1h 30m","to":"1h 42m"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js, err := json.Marshal(tt.view)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(js) != tt.want {
+				t.Errorf("got %s; want %s", js, tt.want)
+			}
+		})
+	}
+}
@@ -22,6 +22,12 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	// The stop channel is closed on shutdown, to end the background jobs which run until then.
+	stop := make(chan struct{})
+
+	// Start the job permanently removing movies that stayed in the trash past the retention period.
+	app.purgeTrash(stop)
+
 	// Start a background goroutine to handle gracefull shutdown.
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -44,6 +50,8 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		close(stop)
+
 		app.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
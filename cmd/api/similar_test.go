@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidateSimilarWeights(t *testing.T) {
+	tests := []struct {
+		name                  string
+		genres, year, runtime float64
+		valid                 bool
+	}{
+		{name: "defaults", genres: 0.6, year: 0.25, runtime: 0.15, valid: true},
+		{name: "single criterion", genres: 0, year: 1, runtime: 0, valid: true},
+		{name: "not normalized", genres: 3, year: 2, runtime: 1, valid: true},
+		{name: "all zero", genres: 0, year: 0, runtime: 0, valid: false},
+		{name: "negative weight", genres: 1, year: -0.5, runtime: 0.5, valid: false},
+		{name: "NaN weight", genres: math.NaN(), year: 1, runtime: 1, valid: false},
+		{name: "infinite weight", genres: 1, year: 1, runtime: math.Inf(1), valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			cfg.similar.genresWeight = tt.genres
+			cfg.similar.yearWeight = tt.year
+			cfg.similar.runtimeWeight = tt.runtime
+
+			err := validateSimilarWeights(cfg)
+			if (err == nil) != tt.valid {
+				t.Errorf("got error %v; want valid %t", err, tt.valid)
+			}
+		})
+	}
+}
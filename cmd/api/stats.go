@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+// statsCacheSize is the maximum number of searches kept in the statistics cache.
+const statsCacheSize = 1_000
+
+// statsCache keeps the statistics of recent searches in memory. The whole cache is
+// invalidated by the models whenever the movies are written to, the ttl only bounding how long
+// entries are kept otherwise. A zero ttl disables the cache.
+type statsCache struct {
+	mu sync.Mutex
+	// generation is incremented on every invalidation, so that statistics computed before a
+	// write, but stored after it, are discarded.
+	generation uint64
+	ttl        time.Duration
+	entries    map[string]statsCacheEntry
+}
+
+type statsCacheEntry struct {
+	stats     *data.MovieStats
+	expiresAt time.Time
+}
+
+func newStatsCache(ttl time.Duration) *statsCache {
+	return &statsCache{
+		ttl:     ttl,
+		entries: make(map[string]statsCacheEntry),
+	}
+}
+
+// get returns the cached statistics for the key, if any, along with the current generation of
+// the cache, to be passed to set.
+func (c *statsCache) get(key string) (*data.MovieStats, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, c.generation, false
+	}
+
+	return entry.stats, c.generation, true
+}
+
+func (c *statsCache) set(key string, generation uint64, stats *data.MovieStats) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := time.Now()
+
+	if len(c.entries) >= statsCacheSize {
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+
+		if len(c.entries) >= statsCacheSize {
+			return
+		}
+	}
+
+	c.entries[key] = statsCacheEntry{stats: stats, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *statsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	clear(c.entries)
+}
+
+// statsCacheKey returns the key of the statistics of a search in the cache, made of every
+// filter of the search under the name of its query string parameter. The genres are sorted, as
+// their order doesn't change the movies matching the search.
+func statsCacheKey(search data.MovieSearch) string {
+	key := url.Values{
+		"title":          {search.Title},
+		"genres":         slices.Sorted(slices.Values(search.Genres)),
+		"exclude_genres": slices.Sorted(slices.Values(search.ExcludeGenres)),
+		"year_min":       {strconv.FormatInt(int64(search.YearMin), 10)},
+		"year_max":       {strconv.FormatInt(int64(search.YearMax), 10)},
+		"runtime_min":    {strconv.FormatInt(int64(search.RuntimeMin), 10)},
+		"runtime_max":    {strconv.FormatInt(int64(search.RuntimeMax), 10)},
+		"person_id":      {strconv.FormatInt(search.PersonID, 10)},
+		"collection_id":  {strconv.FormatInt(search.CollectionID, 10)},
+	}
+
+	return key.Encode()
+}
+
+// showMovieStatsHandler returns aggregates over the movies matching the same search parameters
+// as listMoviesHandler.
+func (app *application) showMovieStatsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	search, err := app.readMovieSearch(r.URL.Query(), v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	key := statsCacheKey(search)
+
+	stats, generation, ok := app.stats.get(key)
+	if !ok {
+		stats, err = app.models.Movies.Stats(search)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.stats.set(key, generation, stats)
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"greenlight.hichammou/internal/data"
+)
+
+func TestStatsCacheKey(t *testing.T) {
+	search := data.MovieSearch{Title: "god", Genres: []string{"drama", "crime"}, ExcludeGenres: []string{"comedy", "action"}, YearMin: 1970}
+	key := statsCacheKey(search)
+
+	reordered := search
+	reordered.Genres = []string{"crime", "drama"}
+	reordered.ExcludeGenres = []string{"action", "comedy"}
+
+	if got := statsCacheKey(reordered); got != key {
+		t.Errorf("got key %q for reordered genres; want %q", got, key)
+	}
+
+	if !slices.Equal(search.Genres, []string{"drama", "crime"}) {
+		t.Errorf("the genres of the search were reordered to %v", search.Genres)
+	}
+
+	others := map[string]data.MovieSearch{
+		"title":          {Title: "godfather", Genres: search.Genres, ExcludeGenres: search.ExcludeGenres, YearMin: 1970},
+		"genres":         {Title: "god", Genres: []string{"drama"}, ExcludeGenres: search.ExcludeGenres, YearMin: 1970},
+		"excluded genre": {Title: "god", Genres: search.ExcludeGenres, ExcludeGenres: search.Genres, YearMin: 1970},
+		"year":           {Title: "god", Genres: search.Genres, ExcludeGenres: search.ExcludeGenres, YearMax: 1970},
+		"person":         {Title: "god", Genres: search.Genres, ExcludeGenres: search.ExcludeGenres, YearMin: 1970, PersonID: 1},
+		"collection":     {Title: "god", Genres: search.Genres, ExcludeGenres: search.ExcludeGenres, YearMin: 1970, CollectionID: 1},
+	}
+
+	for name, other := range others {
+		if statsCacheKey(other) == key {
+			t.Errorf("changing the %s doesn't change the key %q", name, key)
+		}
+	}
+}
+
+func TestStatsCache(t *testing.T) {
+	stats := &data.MovieStats{}
+
+	t.Run("hit", func(t *testing.T) {
+		c := newStatsCache(time.Minute)
+
+		_, generation, ok := c.get("title=god")
+		if ok {
+			t.Fatal("got a hit in an empty cache")
+		}
+
+		c.set("title=god", generation, stats)
+
+		if got, _, ok := c.get("title=god"); !ok || got != stats {
+			t.Errorf("got %v, %t; want the cached statistics", got, ok)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		c := newStatsCache(0)
+		c.set("title=god", 0, stats)
+
+		if _, _, ok := c.get("title=god"); ok {
+			t.Error("got a hit with a zero ttl")
+		}
+	})
+
+	t.Run("invalidated", func(t *testing.T) {
+		c := newStatsCache(time.Minute)
+		c.set("title=god", 0, stats)
+
+		c.invalidate()
+
+		if _, _, ok := c.get("title=god"); ok {
+			t.Error("got a hit after an invalidation")
+		}
+	})
+
+	t.Run("computed before an invalidation", func(t *testing.T) {
+		c := newStatsCache(time.Minute)
+
+		_, generation, _ := c.get("title=god")
+		c.invalidate()
+		c.set("title=god", generation, stats)
+
+		if _, _, ok := c.get("title=god"); ok {
+			t.Error("got a hit for statistics computed before an invalidation")
+		}
+	})
+
+	t.Run("full of fresh entries", func(t *testing.T) {
+		c := newStatsCache(time.Minute)
+		for i := range statsCacheSize {
+			c.entries[fmt.Sprint(i)] = statsCacheEntry{expiresAt: time.Now().Add(time.Minute)}
+		}
+
+		c.set("title=god", 0, stats)
+
+		if _, _, ok := c.get("title=god"); ok {
+			t.Error("the new entry was cached beyond the size of the cache")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"greenlight.hichammou/internal/data"
+	"greenlight.hichammou/internal/validator"
+)
+
+// suggestCacheSize is the maximum number of prefixes kept in the suggestions cache.
+const suggestCacheSize = 10_000
+
+// suggestCache keeps the title suggestions of recently searched prefixes in memory for a short
+// time, as the same prefixes are typed over and over. A zero ttl disables the cache.
+type suggestCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]suggestCacheEntry
+}
+
+type suggestCacheEntry struct {
+	suggestions []*data.MovieSuggestion
+	expiresAt   time.Time
+}
+
+func newSuggestCache(ttl time.Duration) *suggestCache {
+	return &suggestCache{
+		ttl:     ttl,
+		entries: make(map[string]suggestCacheEntry),
+	}
+}
+
+func (c *suggestCache) get(prefix string) ([]*data.MovieSuggestion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[prefix]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.suggestions, true
+}
+
+func (c *suggestCache) set(prefix string, suggestions []*data.MovieSuggestion) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	// Make room by evicting the expired entries once the cache is full. If they were all
+	// still fresh, the new entry simply isn't cached.
+	if len(c.entries) >= suggestCacheSize {
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+
+		if len(c.entries) >= suggestCacheSize {
+			return
+		}
+	}
+
+	c.entries[prefix] = suggestCacheEntry{suggestions: suggestions, expiresAt: now.Add(c.ttl)}
+}
+
+func (app *application) suggestMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	// Normalize the prefix, so that the cache is shared by the same search typed differently.
+	q := strings.ToLower(strings.Join(strings.Fields(r.URL.Query().Get("q")), " "))
+
+	v := validator.New()
+
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+
+	if !v.Valide() {
+		app.faildValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, ok := app.suggestions.get(q)
+	if !ok {
+		var err error
+
+		suggestions, err = app.models.Movies.Suggest(q, app.config.suggest.limit)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.suggestions.set(q, suggestions)
+	}
+
+	err := app.writeResponse(w, r, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
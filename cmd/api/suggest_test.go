@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"greenlight.hichammou/internal/data"
+)
+
+func TestSuggestCache(t *testing.T) {
+	suggestions := []*data.MovieSuggestion{{ID: 1, Title: "The Godfather", Year: 1972}}
+
+	t.Run("hit", func(t *testing.T) {
+		c := newSuggestCache(time.Minute)
+		c.set("the god", suggestions)
+
+		got, ok := c.get("the god")
+		if !ok || len(got) != 1 || got[0].ID != 1 {
+			t.Errorf("got %v, %t; want the cached suggestions", got, ok)
+		}
+
+		if _, ok := c.get("the go"); ok {
+			t.Error("got a hit for another prefix")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		c := newSuggestCache(0)
+		c.set("the god", suggestions)
+
+		if _, ok := c.get("the god"); ok {
+			t.Error("got a hit with a zero ttl")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		c := newSuggestCache(time.Minute)
+		c.entries["the god"] = suggestCacheEntry{suggestions: suggestions, expiresAt: time.Now().Add(-time.Second)}
+
+		if _, ok := c.get("the god"); ok {
+			t.Error("got a hit for an expired entry")
+		}
+	})
+
+	t.Run("full of expired entries", func(t *testing.T) {
+		c := newSuggestCache(time.Minute)
+		for i := range suggestCacheSize {
+			c.entries[fmt.Sprint(i)] = suggestCacheEntry{expiresAt: time.Now().Add(-time.Second)}
+		}
+
+		c.set("the god", suggestions)
+
+		if _, ok := c.get("the god"); !ok {
+			t.Error("the new entry wasn't cached")
+		}
+		if len(c.entries) != 1 {
+			t.Errorf("got %d entries; want the expired ones evicted", len(c.entries))
+		}
+	})
+
+	t.Run("full of fresh entries", func(t *testing.T) {
+		c := newSuggestCache(time.Minute)
+		for i := range suggestCacheSize {
+			c.entries[fmt.Sprint(i)] = suggestCacheEntry{expiresAt: time.Now().Add(time.Minute)}
+		}
+
+		c.set("the god", suggestions)
+
+		if _, ok := c.get("the god"); ok {
+			t.Error("the new entry was cached beyond the size of the cache")
+		}
+		if len(c.entries) != suggestCacheSize {
+			t.Errorf("got %d entries; want %d", len(c.entries), suggestCacheSize)
+		}
+	})
+}
+
+func TestSuggestMoviesHandler(t *testing.T) {
+	// The suggestions are served from the cache, so the handler never reaches the database.
+	app := &application{suggestions: newSuggestCache(time.Minute)}
+	app.suggestions.set("the god", []*data.MovieSuggestion{{ID: 1, Title: "The Godfather", Year: 1972}})
+
+	tests := []struct {
+		q    string
+		code int
+		body string
+	}{
+		{q: "The%20%20God%20", code: http.StatusOK, body: `"title": "The Godfather"`},
+		{q: "", code: http.StatusUnprocessableEntity, body: "must be provided"},
+		{q: "%20%20", code: http.StatusUnprocessableEntity, body: "must be provided"},
+		{q: strings.Repeat("a", 101), code: http.StatusUnprocessableEntity, body: "must not be more than 100 bytes long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/movies/suggest?q="+tt.q, nil)
+
+			app.suggestMoviesHandler(rr, r)
+
+			if rr.Code != tt.code {
+				t.Fatalf("got status %d; want %d", rr.Code, tt.code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.body) {
+				t.Errorf("got body %s; want it to contain %s", rr.Body, tt.body)
+			}
+		})
+	}
+}
@@ -15,7 +15,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		Password string `json:"password"`
 	}
 
-	err := app.readJSON(w, r, &input)
+	err := app.readRequest(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -60,7 +60,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	err = app.writeResponse(w, r, http.StatusCreated, envelope{"authentication_token": token}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -71,7 +71,7 @@ func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r
 		Email string `json:"email"`
 	}
 
-	err := app.readJSON(w, r, &input)
+	err := app.readRequest(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -120,7 +120,7 @@ func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r
 	})
 
 	env := envelope{"message": "an email will be sent to you containing password reset instructions"}
-	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	err = app.writeResponse(w, r, http.StatusAccepted, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -131,7 +131,7 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 		Email string `json:"email"`
 	}
 
-	err := app.readJSON(w, r, &input)
+	err := app.readRequest(w, r, &input)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -180,7 +180,7 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 	})
 
 	env := envelope{"message": "an email containing the activation token has sent to your mail box."}
-	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	err = app.writeResponse(w, r, http.StatusAccepted, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
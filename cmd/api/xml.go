@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The encodeXML() helper encodes the JSON representation of v in a <response> element. Object
+// members become elements named after their key, or <member name="..."> elements for the keys
+// which aren't valid XML names, array values become <item> elements, and nulls are marked with
+// a nil="true" attribute.
+func encodeXML(v any) ([]byte, error) {
+	tree, err := toJSONTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := xml.MarshalIndent(xmlElement{name: "response", value: tree}, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	body := append([]byte(xml.Header), out...)
+
+	return append(body, '\n'), nil
+}
+
+// xmlElement is an element of the XML encoding of a JSON tree, named after the key of its
+// value. It implements the xml.Marshaler interface, so that the tree is encoded by encoding/xml.
+type xmlElement struct {
+	name  string
+	value any
+}
+
+func (e xmlElement) MarshalXML(enc *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Local: e.name}}
+	if !isXMLName(e.name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "member"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: e.name}},
+		}
+	}
+
+	var children []xmlElement
+
+	switch node := e.value.(type) {
+	case jsonObject:
+		for _, member := range node {
+			children = append(children, xmlElement{name: member.Key, value: member.Value})
+		}
+
+	case []any:
+		for _, value := range node {
+			children = append(children, xmlElement{name: "item", value: value})
+		}
+
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+		return enc.EncodeElement("", start)
+
+	default:
+		return enc.EncodeElement(fmt.Sprint(node), start)
+	}
+
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		err = enc.Encode(child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// isXMLName reports whether s can be used as an element name. It is stricter than the XML
+// specification, which allows more characters than the ones used by the keys of responses.
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+
+	for i, c := range s {
+		switch {
+		case unicode.IsLetter(c) || c == '_':
+		case i > 0 && (unicode.IsDigit(c) || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// The decodeXML() helper converts an XML request body, in the format written by encodeXML(),
+// into JSON for the decoding of dst. XML has no types, so the type of dst tells which elements
+// are arrays and which values are numbers or booleans. Elements which don't match a field of
+// dst are kept as strings, for the decoding to reject them as unknown keys. The name of the
+// root element doesn't matter.
+func decodeXML(body []byte, dst any) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+
+	var root *xmlNode
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if root == nil {
+				return nil, fmt.Errorf("body contains badly-formed XML: %v", err)
+			}
+			break
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if root != nil {
+				return nil, fmt.Errorf("body must contain only one root XML element")
+			}
+
+			root, err = readXMLNode(dec, token)
+			if err != nil {
+				return nil, fmt.Errorf("body contains badly-formed XML: %v", err)
+			}
+		case xml.CharData:
+			if len(bytes.TrimSpace(token)) > 0 {
+				return nil, fmt.Errorf("body contains text outside of the root XML element")
+			}
+		}
+	}
+
+	return json.Marshal(root.value(reflect.TypeOf(dst)))
+}
+
+type xmlNode struct {
+	name     string
+	nil      bool
+	text     string
+	children []*xmlNode
+}
+
+func readXMLNode(dec *xml.Decoder, start xml.StartElement) (*xmlNode, error) {
+	node := &xmlNode{name: start.Name.Local}
+
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Local == "name" && node.name == "member":
+			node.name = attr.Value
+		case attr.Name.Local == "nil":
+			node.nil = attr.Value == "true"
+		}
+	}
+
+	var text strings.Builder
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := readXMLNode(dec, token)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			node.text = strings.TrimSpace(text.String())
+			return node, nil
+		}
+	}
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// value returns the JSON value of the node for the decoding of a value of type t.
+func (n *xmlNode) value(t reflect.Type) any {
+	if n.nil {
+		return nil
+	}
+
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Types which decode themselves, like data.Runtime, accept strings.
+	if t == nil || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return n.text
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		object := map[string]any{}
+
+		for _, child := range n.children {
+			var fieldType reflect.Type
+
+			for name, ft := range fields {
+				if strings.EqualFold(name, child.name) {
+					fieldType = ft
+					break
+				}
+			}
+
+			object[child.name] = child.value(fieldType)
+		}
+
+		return object
+
+	case reflect.Map:
+		object := map[string]any{}
+
+		for _, child := range n.children {
+			object[child.name] = child.value(t.Elem())
+		}
+
+		return object
+
+	case reflect.Slice, reflect.Array:
+		array := []any{}
+
+		for _, child := range n.children {
+			array = append(array, child.value(t.Elem()))
+		}
+
+		return array
+
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(n.text); err == nil {
+			return b
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if json.Valid([]byte(n.text)) {
+			if _, err := strconv.ParseFloat(n.text, 64); err == nil {
+				return json.Number(n.text)
+			}
+		}
+	}
+
+	return n.text
+}
+
+// jsonFields returns the types of the fields of a struct, by the key they are decoded from,
+// including the fields of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFields(ft) {
+				if _, ok := fields[embeddedName]; !ok {
+					fields[embeddedName] = embeddedType
+				}
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields[name] = field.Type
+	}
+
+	return fields
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"greenlight.hichammou/internal/data"
+)
+
+func TestDecodeXML(t *testing.T) {
+	// dst mirrors the input of createMovieHandler.
+	type movieInput struct {
+		Title   string            `json:"title"`
+		Year    int32             `json:"year"`
+		Runtime data.Runtime      `json:"runtime"`
+		Genres  []string          `json:"genres"`
+		Draft   *bool             `json:"draft"`
+		Titles  map[string]string `json:"titles"`
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "typed values",
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<response>
+	<title>Casablanca</title>
+	<year>1942</year>
+	<runtime>1h 42m</runtime>
+	<genres><item>drama</item><item>romance</item></genres>
+	<draft>true</draft>
+</response>`,
+			want: `{"draft":true,"genres":["drama","romance"],"runtime":"1h 42m","title":"Casablanca","year":1942}`,
+		},
+		{
+			name: "any root element and case insensitive fields",
+			body: `<movie><Title>Casablanca</Title><YEAR>1942</YEAR></movie>`,
+			want: `{"Title":"Casablanca","YEAR":1942}`,
+		},
+		{
+			name: "member names and maps",
+			body: `<response><titles><member name="fr-FR">Casablanca</member></titles></response>`,
+			want: `{"titles":{"fr-FR":"Casablanca"}}`,
+		},
+		{
+			name: "nil and empty array",
+			body: `<response><draft nil="true"/><genres></genres></response>`,
+			want: `{"draft":null,"genres":[]}`,
+		},
+		{
+			name: "invalid number kept as string",
+			body: `<response><year>nineteen</year></response>`,
+			want: `{"year":"nineteen"}`,
+		},
+		{
+			name: "unknown element kept as string",
+			body: `<response><director>Curtiz</director></response>`,
+			want: `{"director":"Curtiz"}`,
+		},
+		{
+			name:    "badly-formed",
+			body:    `<response><title>Casablanca</response>`,
+			wantErr: "body contains badly-formed XML",
+		},
+		{
+			name:    "empty",
+			body:    ``,
+			wantErr: "body contains badly-formed XML",
+		},
+		{
+			name:    "two root elements",
+			body:    `<response></response><response></response>`,
+			wantErr: "body must contain only one root XML element",
+		},
+		{
+			name:    "text outside the root element",
+			body:    `<response></response>movie`,
+			wantErr: "body contains text outside of the root XML element",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeXML([]byte(tt.body), &movieInput{})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeXML(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{
+			name: "objects and arrays",
+			v:    envelope{"movie": map[string]any{"id": 1, "title": "Tom & Jerry", "genres": []string{"comedy", "animation"}}},
+			want: "<response>\n\t<movie>\n\t\t<genres>\n\t\t\t<item>comedy</item>\n\t\t\t<item>animation</item>\n\t\t</genres>\n\t\t<id>1</id>\n\t\t<title>Tom &amp; Jerry</title>\n\t</movie>\n</response>\n",
+		},
+		{
+			name: "keys which aren't XML names",
+			v:    envelope{"titles": map[string]any{"fr-FR": "Casablanca", "1st": nil}},
+			want: "<response>\n\t<titles>\n\t\t<member name=\"1st\" nil=\"true\"></member>\n\t\t<fr-FR>Casablanca</fr-FR>\n\t</titles>\n</response>\n",
+		},
+		{
+			name: "empty values",
+			v:    envelope{"genres": []string{}, "metadata": map[string]any{}},
+			want: "<response>\n\t<genres></genres>\n\t<metadata></metadata>\n</response>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeXML(tt.v)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + tt.want
+			if string(got) != want {
+				t.Errorf("got %q; want %q", got, want)
+			}
+
+			// The encoded body must be accepted back.
+			_, err = decodeXML(got, &map[string]any{})
+			if err != nil {
+				t.Errorf("decoding the encoded body: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"greenlight.hichammou/internal/validator"
+)
+
+var (
+	ErrUnknownMovie      = errors.New("unknown movie")
+	ErrMovieInCollection = errors.New("movie in another collection")
+)
+
+// Collection groups movies of a series, such as a trilogy, in a given order. MovieIDs lists
+// its movies in that order, including the ones in the trash.
+type Collection struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MovieIDs    []int64   `json:"movie_ids"`
+	Movies      []*Movie  `json:"movies,omitempty"` // only loaded when showing a single collection
+	Version     int32     `json:"version"`
+}
+
+// MovieCollection is the collection a movie belongs to, as embedded in the movie. Position is
+// the rank of the movie in the collection, starting at 1.
+type MovieCollection struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Size     int    `json:"size"`
+}
+
+type CollectionModel struct {
+	DB             *sql.DB
+	onSearchChange searchListener
+}
+
+func ValidateCollection(v *validator.Validator, collection *Collection) {
+	v.Check(collection.Name != "", "name", "must be provided")
+	v.Check(len(collection.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(len(collection.Description) <= 2000, "description", "must not be more than 2000 bytes long")
+
+	v.Check(collection.MovieIDs != nil, "movie_ids", "must be provided")
+	v.Check(len(collection.MovieIDs) <= 500, "movie_ids", "must not contain more than 500 movies")
+	v.Check(validator.Unique(collection.MovieIDs), "movie_ids", "must not contain duplicate values")
+
+	for _, id := range collection.MovieIDs {
+		if id < 1 {
+			v.AddError("movie_ids", "must only contain positive integers")
+			break
+		}
+	}
+}
+
+// collectionColumns selects a collection along with the ids of its movies in order. The
+// collection_movies table must be left joined as cm, and the rows grouped by collection.
+const collectionColumns = `c.id, c.created_at, c.name, c.description, c.version,
+						COALESCE(array_agg(cm.movie_id ORDER BY cm.position) FILTER (WHERE cm.movie_id IS NOT NULL), '{}')`
+
+// Insert adds a collection along with its movies in a single transaction.
+func (m CollectionModel) Insert(collection *Collection) error {
+	query := `INSERT INTO collections (name, description)
+						VALUES ($1, $2)
+						RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, collection.Name, collection.Description).Scan(&collection.ID, &collection.CreatedAt, &collection.Version)
+	if err != nil {
+		return err
+	}
+
+	err = insertCollectionMovies(ctx, tx, collection.ID, collection.MovieIDs)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// insertCollectionMovies adds the movies to a collection, in the given order. It returns
+// ErrUnknownMovie if one of them doesn't exist, and ErrMovieInCollection if one of them
+// already belongs to another collection.
+func insertCollectionMovies(ctx context.Context, tx *sql.Tx, collectionID int64, movieIDs []int64) error {
+	query := `INSERT INTO collection_movies (collection_id, movie_id, position)
+						SELECT $1, m.id, m.position
+						FROM unnest($2::bigint[]) WITH ORDINALITY AS m(id, position)`
+
+	_, err := tx.ExecContext(ctx, query, collectionID, pq.Array(movieIDs))
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "collection_movies" violates foreign key constraint "collection_movies_movie_id_fkey"`:
+			return ErrUnknownMovie
+		case err.Error() == `pq: duplicate key value violates unique constraint "collection_movies_movie_id_key"`:
+			return ErrMovieInCollection
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CollectionModel) Get(id int64) (*Collection, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := fmt.Sprintf(`SELECT %s
+						FROM collections c
+						LEFT JOIN collection_movies cm ON cm.collection_id = c.id
+						WHERE c.id = $1
+						GROUP BY c.id`, collectionColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var collection Collection
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&collection.ID,
+		&collection.CreatedAt,
+		&collection.Name,
+		&collection.Description,
+		&collection.Version,
+		pq.Array(&collection.MovieIDs),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &collection, nil
+}
+
+func (m CollectionModel) List(name string, filters Filters) ([]*Collection, Metadata, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), %s
+						FROM collections c
+						LEFT JOIN collection_movies cm ON cm.collection_id = c.id
+						WHERE (to_tsvector('simple', c.name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+						GROUP BY c.id
+						ORDER BY c.%s %s, c.id ASC
+						LIMIT $2 OFFSET $3`, collectionColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	collections := make([]*Collection, 0)
+
+	for rows.Next() {
+		var collection Collection
+
+		err = rows.Scan(
+			&totalRecords,
+			&collection.ID,
+			&collection.CreatedAt,
+			&collection.Name,
+			&collection.Description,
+			&collection.Version,
+			pq.Array(&collection.MovieIDs),
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		collections = append(collections, &collection)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return collections, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update saves a collection and replaces its movies, in their new order, in a single
+// transaction. Like MovieModel.Update, it returns ErrEditConflict if the collection was
+// modified since it was read, so that concurrent reorderings can't overwrite each other.
+func (m CollectionModel) Update(collection *Collection) error {
+	query := `UPDATE collections
+						SET name = $1, description = $2, version = version + 1
+						WHERE id = $3 AND version = $4
+						RETURNING version`
+
+	args := []any{collection.Name, collection.Description, collection.ID, collection.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM collection_movies WHERE collection_id = $1`, collection.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertCollectionMovies(ctx, tx, collection.ID, collection.MovieIDs)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// Delete removes a collection. Its movies are kept, they just don't belong to it anymore.
+func (m CollectionModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM collections WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// GetForMovie returns the collection a movie belongs to, or nil if it doesn't belong to any.
+func (m CollectionModel) GetForMovie(movieID int64) (*MovieCollection, error) {
+	query := `SELECT c.id, c.name, cm.position,
+						(SELECT COUNT(*) FROM collection_movies WHERE collection_id = c.id)
+						FROM collection_movies cm
+						INNER JOIN collections c ON c.id = cm.collection_id
+						WHERE cm.movie_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var collection MovieCollection
+
+	err := m.DB.QueryRowContext(ctx, query, movieID).Scan(
+		&collection.ID,
+		&collection.Name,
+		&collection.Position,
+		&collection.Size,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return &collection, nil
+}
@@ -0,0 +1,42 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestValidateCollection(t *testing.T) {
+	tooMany := make([]int64, 501)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name       string
+		collection Collection
+		valid      bool
+	}{
+		{name: "valid", collection: Collection{Name: "The Godfather Trilogy", MovieIDs: []int64{3, 1, 2}}, valid: true},
+		{name: "no movies", collection: Collection{Name: "Upcoming", MovieIDs: []int64{}}, valid: true},
+		{name: "missing name", collection: Collection{MovieIDs: []int64{1}}, valid: false},
+		{name: "name too long", collection: Collection{Name: strings.Repeat("a", 501), MovieIDs: []int64{1}}, valid: false},
+		{name: "description too long", collection: Collection{Name: "Trilogy", Description: strings.Repeat("a", 2001), MovieIDs: []int64{1}}, valid: false},
+		{name: "missing movie ids", collection: Collection{Name: "Trilogy"}, valid: false},
+		{name: "too many movies", collection: Collection{Name: "Trilogy", MovieIDs: tooMany}, valid: false},
+		{name: "duplicate movies", collection: Collection{Name: "Trilogy", MovieIDs: []int64{1, 2, 1}}, valid: false},
+		{name: "invalid movie id", collection: Collection{Name: "Trilogy", MovieIDs: []int64{1, 0}}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCollection(v, &tt.collection)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
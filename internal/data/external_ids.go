@@ -0,0 +1,104 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+var (
+	ErrDuplicateExternalID = errors.New("duplicate external id")
+)
+
+// ExternalIDs maps the providers of third-party datasets, such as "imdb", to the identifier of
+// a movie in them.
+type ExternalIDs map[string]string
+
+// externalIDsColumn selects the external identifiers of the movie of the current row as a
+// JSON object, to be scanned into ExternalIDs.
+const externalIDsColumn = `COALESCE((SELECT jsonb_object_agg(provider, value) FROM external_ids WHERE movie_id = movies.id), '{}')`
+
+func (ids *ExternalIDs) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, ids)
+	case string:
+		return json.Unmarshal([]byte(v), ids)
+	default:
+		return fmt.Errorf("unsupported external ids type %T", src)
+	}
+}
+
+func ValidateExternalIDs(v *validator.Validator, ids ExternalIDs) {
+	for provider, value := range ids {
+		key := "external_ids." + provider
+
+		if _, ok := validator.ExternalIDRX[provider]; !ok {
+			v.AddError(key, "unknown provider")
+			continue
+		}
+
+		v.Check(validator.ExternalID(provider, value), key, "must be a valid "+provider+" identifier")
+	}
+}
+
+// replaceExternalIDs replaces the external identifiers of a movie. It returns
+// ErrDuplicateExternalID if one of them already belongs to another movie.
+func replaceExternalIDs(ctx context.Context, tx *sql.Tx, movieID int64, ids ExternalIDs) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM external_ids WHERE movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	// Insert the identifiers in a stable order, so that concurrent writes lock them in the same order.
+	providers := make([]string, 0, len(ids))
+	for provider := range ids {
+		providers = append(providers, provider)
+	}
+	slices.Sort(providers)
+
+	query := `INSERT INTO external_ids (movie_id, provider, value) VALUES ($1, $2, $3)`
+
+	for _, provider := range providers {
+		_, err = tx.ExecContext(ctx, query, movieID, provider, ids[provider])
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "external_ids_provider_value_key"`:
+				return ErrDuplicateExternalID
+			default:
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetByExternalID returns the movie with the given identifier in a third-party dataset. It
+// returns ErrRecordNotFound if there is none, or if it's in the trash.
+func (m MovieModel) GetByExternalID(provider, value string) (*Movie, error) {
+	query := `SELECT movie_id FROM external_ids WHERE provider = $1 AND value = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+
+	err := m.DB.QueryRowContext(ctx, query, provider, value).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return m.Get(id)
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestValidateExternalIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  ExternalIDs
+		want []string
+	}{
+		{name: "none", ids: nil},
+		{name: "valid", ids: ExternalIDs{"imdb": "tt0068646", "tmdb": "238", "wikidata": "Q47703"}},
+		{name: "malformed", ids: ExternalIDs{"imdb": "68646", "tmdb": "238"}, want: []string{"external_ids.imdb"}},
+		{name: "unknown provider", ids: ExternalIDs{"letterboxd": "the-godfather"}, want: []string{"external_ids.letterboxd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateExternalIDs(v, tt.ids)
+
+			if got := slices.Sorted(maps.Keys(v.Errors)); !slices.Equal(got, tt.want) {
+				t.Errorf("got errors %v; want errors for %v", v.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalIDsScan(t *testing.T) {
+	for _, src := range []any{[]byte(`{"imdb": "tt0068646"}`), `{"imdb": "tt0068646"}`} {
+		var ids ExternalIDs
+
+		err := ids.Scan(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ids["imdb"] != "tt0068646" || len(ids) != 1 {
+			t.Errorf("got %v from %T", ids, src)
+		}
+	}
+
+	var ids ExternalIDs
+	if err := ids.Scan(42); err == nil {
+		t.Error("got no error scanning an int")
+	}
+}
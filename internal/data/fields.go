@@ -0,0 +1,79 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+	"greenlight.hichammou/internal/validator"
+)
+
+// MovieFieldsSafelist lists the fields of a movie which can be requested with a fieldset when
+// showing it.
+var MovieFieldsSafelist = []string{"id", "title", "original_title", "year", "runtime", "genres", "version", "rating", "poster_url", "match_score", "collection", "external_ids"}
+
+// MovieListFieldsSafelist lists the fields which can be requested when listing movies. The
+// collection of a movie is only loaded when showing it on its own.
+var MovieListFieldsSafelist = []string{"id", "title", "original_title", "year", "runtime", "genres", "version", "rating", "poster_url", "match_score", "external_ids"}
+
+// movieColumn is a column selected by the queries listing movies. Its field is the name of the
+// field it backs in a fieldset, empty when it can't be requested on its own.
+type movieColumn struct {
+	field  string
+	column string
+	dest   func(movie *Movie) []any
+}
+
+var movieColumns = []movieColumn{
+	{"id", "id", func(movie *Movie) []any { return []any{&movie.ID} }},
+	{"", "created_at", func(movie *Movie) []any { return []any{&movie.CreatedAt} }},
+	{"title", "title", func(movie *Movie) []any { return []any{&movie.Title} }},
+	{"year", "year", func(movie *Movie) []any { return []any{&movie.Year} }},
+	{"runtime", "runtime", func(movie *Movie) []any { return []any{&movie.Runtime} }},
+	{"genres", "genres", func(movie *Movie) []any { return []any{pq.Array(&movie.Genres)} }},
+	{"version", "version", func(movie *Movie) []any { return []any{&movie.Version} }},
+	{"rating", "rating, rating_count", func(movie *Movie) []any { return []any{&movie.Rating.Average, &movie.Rating.Count} }},
+	{"poster_url", "poster_key", func(movie *Movie) []any { return []any{&movie.PosterURL} }},
+	{"external_ids", externalIDsColumn, func(movie *Movie) []any { return []any{&movie.ExternalIDs} }},
+}
+
+// movieFieldset selects the columns backing the given fields, or all of them when fields is
+// empty. It returns the list of columns for the SELECT clause, and a function returning the
+// destinations to scan a row into, in the same order. The id is always selected, along with
+// the columns of the extra fields, which the query needs internally.
+func movieFieldset(fields []string, extra ...string) (string, func(movie *Movie) []any) {
+	var (
+		columns  []string
+		selected []movieColumn
+	)
+
+	for _, c := range movieColumns {
+		wanted := len(fields) == 0 || c.field == "id" || validator.In(c.field, fields...) || validator.In(c.field, extra...)
+
+		// The original title is the title column as well, the localized one replacing it later.
+		if c.field == "title" && validator.In("original_title", fields...) {
+			wanted = true
+		}
+
+		if wanted {
+			columns = append(columns, c.column)
+			selected = append(selected, c)
+		}
+	}
+
+	dest := func(movie *Movie) []any {
+		var dest []any
+		for _, c := range selected {
+			dest = append(dest, c.dest(movie)...)
+		}
+		return dest
+	}
+
+	return strings.Join(columns, ", "), dest
+}
+
+func ValidateFields(v *validator.Validator, fields []string, safelist []string) {
+	for _, field := range fields {
+		v.Check(validator.In(field, safelist...), "fields", "invalid field value: "+field)
+	}
+	v.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestValidateFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []string
+		safelist []string
+		valid    bool
+	}{
+		{name: "no fields", fields: nil, safelist: MovieListFieldsSafelist, valid: true},
+		{name: "list fields", fields: []string{"id", "title", "match_score"}, safelist: MovieListFieldsSafelist, valid: true},
+		{name: "collection when showing", fields: []string{"title", "collection"}, safelist: MovieFieldsSafelist, valid: true},
+		{name: "collection when listing", fields: []string{"title", "collection"}, safelist: MovieListFieldsSafelist, valid: false},
+		{name: "unknown field", fields: []string{"plot"}, safelist: MovieFieldsSafelist, valid: false},
+		{name: "duplicate field", fields: []string{"year", "year"}, safelist: MovieFieldsSafelist, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateFields(v, tt.fields, tt.safelist)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMovieFieldset(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		extra  []string
+		want   string
+		dest   int
+	}{
+		{name: "all fields", want: "id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key, " + externalIDsColumn, dest: 11},
+		{name: "id always selected", fields: []string{"year"}, want: "id, year", dest: 2},
+		{name: "original title", fields: []string{"original_title"}, want: "id, title", dest: 2},
+		{name: "extra columns", fields: []string{"title"}, extra: []string{"version", "rating"}, want: "id, title, version, rating, rating_count", dest: 5},
+		{name: "fields without columns", fields: []string{"collection", "match_score"}, want: "id", dest: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, dest := movieFieldset(tt.fields, tt.extra...)
+
+			if columns != tt.want {
+				t.Errorf("got columns %q; want %q", columns, tt.want)
+			}
+
+			if got := len(dest(&Movie{})); got != tt.dest {
+				t.Errorf("got %d destinations; want %d", got, tt.dest)
+			}
+		})
+	}
+}
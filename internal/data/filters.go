@@ -1,6 +1,9 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"math"
 	"strings"
 
@@ -22,6 +25,52 @@ type Metadata struct {
 	TotalRecords int `json:"total_records,omitempty"`
 }
 
+// CursorMetadata is returned instead of Metadata when a list is paginated with a cursor.
+// It doesn't carry a total count, as computing it would defeat the purpose of keyset
+// pagination.
+type CursorMetadata struct {
+	PageSize   int    `json:"page_size"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the position of the last record of a page when using keyset pagination. It
+// holds the sort it was generated for, the value of the sort column and the id of that
+// record, which is used as a tie-breaker.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// Encode returns the opaque representation of the cursor sent to the client.
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// DecodeCursor parses a cursor previously returned by Encode. An empty string decodes into
+// a nil cursor, which means the first page.
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
@@ -46,12 +95,27 @@ func (f Filters) sortColumn() string {
 }
 
 func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
+	// Sorting by relevance always puts the best matches first.
+	if strings.HasPrefix(f.Sort, "-") || f.Sort == "relevance" {
 		return "DESC"
 	}
 	return "ASC"
 }
 
+// keysetCondition returns the condition selecting the records that come after the cursor in
+// the current sort order. The cursor value and id are bound to the valuePlaceholder and
+// idPlaceholder parameters. The id is always sorted in ascending order as a tie-breaker.
+func (f Filters) keysetCondition(valuePlaceholder, idPlaceholder string) string {
+	column := f.sortColumn()
+
+	operator := ">"
+	if f.sortDirection() == "DESC" {
+		operator = "<"
+	}
+
+	return "(" + column + " " + operator + " " + valuePlaceholder + " OR (" + column + " = " + valuePlaceholder + " AND id > " + idPlaceholder + "))"
+}
+
 func (f Filters) limit() int {
 	return f.PageSize
 }
@@ -69,3 +133,19 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	// Check that the sort parameters matches a value in the safelist
 	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
 }
+
+// ValidateCursor checks that the cursor was generated for the sort currently requested, and
+// that its value has the type of the sort column, as cursors come back from clients.
+func ValidateCursor(v *validator.Validator, c *Cursor, f Filters) {
+	if c == nil {
+		return
+	}
+
+	if c.Sort != f.Sort {
+		v.AddError("cursor", "does not match the sort parameter")
+		return
+	}
+
+	_, err := parseSortValue(strings.TrimPrefix(c.Sort, "-"), c.Value)
+	v.Check(err == nil, "cursor", "must be a cursor returned by a previous request")
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	encode := func(js string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(js))
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    *Cursor
+		wantErr bool
+	}{
+		{name: "first page", input: "", want: nil},
+		{name: "encoded cursor", input: Cursor{Sort: "-year", Value: "1942", ID: 7}.Encode(), want: &Cursor{Sort: "-year", Value: "1942", ID: 7}},
+		{name: "title with symbols", input: Cursor{Sort: "title", Value: "Ça/va? \"oui\"", ID: 1}.Encode(), want: &Cursor{Sort: "title", Value: "Ça/va? \"oui\"", ID: 1}},
+		{name: "padded base64", input: base64.URLEncoding.EncodeToString([]byte(`{"s":"id","v":"1","id":1}`)) + "=", wantErr: true},
+		{name: "standard base64", input: "+/+/", wantErr: true},
+		{name: "not base64", input: "not a cursor!", wantErr: true},
+		{name: "not JSON", input: encode("id=1"), wantErr: true},
+		{name: "wrong types", input: encode(`{"s":"id","v":1,"id":"1"}`), wantErr: true},
+		{name: "missing id", input: encode(`{"s":"id","v":"1"}`), wantErr: true},
+		{name: "negative id", input: encode(`{"s":"id","v":"1","id":-1}`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.input)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Errorf("got %+v, %v; want ErrInvalidCursor", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor *Cursor
+		sort   string
+		valid  bool
+	}{
+		{name: "no cursor", cursor: nil, sort: "id", valid: true},
+		{name: "id", cursor: &Cursor{Sort: "id", Value: "42", ID: 42}, sort: "id", valid: true},
+		{name: "year", cursor: &Cursor{Sort: "-year", Value: "1942", ID: 1}, sort: "-year", valid: true},
+		{name: "title", cursor: &Cursor{Sort: "title", Value: "Casablanca", ID: 1}, sort: "title", valid: true},
+		{name: "rating", cursor: &Cursor{Sort: "rating", Value: "4.333333333333333", ID: 1}, sort: "rating", valid: true},
+		{name: "other sort", cursor: &Cursor{Sort: "year", Value: "1942", ID: 1}, sort: "-year", valid: false},
+		{name: "string year", cursor: &Cursor{Sort: "year", Value: "nineteen", ID: 1}, sort: "year", valid: false},
+		{name: "year out of range", cursor: &Cursor{Sort: "year", Value: "4294969296", ID: 1}, sort: "year", valid: false},
+		{name: "NaN rating", cursor: &Cursor{Sort: "rating", Value: "NaN", ID: 1}, sort: "rating", valid: false},
+		{name: "title with NUL", cursor: &Cursor{Sort: "title", Value: "a\x00b", ID: 1}, sort: "title", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+
+			ValidateCursor(v, tt.cursor, Filters{Sort: tt.sort})
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid = %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
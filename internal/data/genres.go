@@ -0,0 +1,418 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"greenlight.hichammou/internal/validator"
+)
+
+var (
+	ErrDuplicateGenre = errors.New("duplicate genre")
+	ErrGenreInUse     = errors.New("genre in use")
+)
+
+// Genre is an entry of the genre taxonomy. Movies reference genres by their slug, which never
+// changes once the genre is created. Aliases are the other names a genre is known by, and are
+// resolved to its slug when a movie is validated.
+type Genre struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Version   int32     `json:"version"`
+}
+
+// GenreTaxonomy maps the slug and the aliases of every genre to the slug of the genre.
+type GenreTaxonomy map[string]string
+
+// Resolve returns the slug of the genre known by the given name, if there is one.
+func (t GenreTaxonomy) Resolve(name string) (string, bool) {
+	slug, ok := t[GenreSlug(name)]
+	return slug, ok
+}
+
+// GenreSlug normalizes a genre name into a slug: lowercase words separated by dashes, so that
+// "Sci-Fi", "sci fi" and "SCI_FI" all become "sci-fi". It matches the normalization applied to
+// the existing movies by the migration creating the genres table.
+func GenreSlug(name string) string {
+	var b strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return b.String()
+}
+
+type GenreModel struct {
+	DB             *sql.DB
+	onSearchChange searchListener
+}
+
+// ValidateGenre checks a genre, normalizing its aliases into slugs on the way.
+func ValidateGenre(v *validator.Validator, genre *Genre) {
+	v.Check(genre.Slug != "", "slug", "must be provided")
+	v.Check(len(genre.Slug) <= 100, "slug", "must not be more than 100 bytes long")
+	v.Check(genre.Slug == GenreSlug(genre.Slug), "slug", "must only contain lowercase letters, digits and dashes")
+
+	v.Check(genre.Name != "", "name", "must be provided")
+	v.Check(len(genre.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(genre.Aliases != nil, "aliases", "must be provided")
+	v.Check(len(genre.Aliases) <= 20, "aliases", "must not contain more than 20 aliases")
+
+	for i, alias := range genre.Aliases {
+		genre.Aliases[i] = GenreSlug(alias)
+
+		v.Check(genre.Aliases[i] != "", "aliases", "must not contain empty values")
+		v.Check(len(genre.Aliases[i]) <= 100, "aliases", "must not contain values more than 100 bytes long")
+		v.Check(genre.Aliases[i] != genre.Slug, "aliases", "must not contain the slug of the genre")
+	}
+
+	v.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+}
+
+// Taxonomy loads the whole genre taxonomy, to validate the genres of movies against it.
+func (m GenreModel) Taxonomy() (GenreTaxonomy, error) {
+	query := `SELECT slug, aliases FROM genres`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	taxonomy := make(GenreTaxonomy)
+
+	for rows.Next() {
+		var (
+			slug    string
+			aliases []string
+		)
+
+		err = rows.Scan(&slug, pq.Array(&aliases))
+		if err != nil {
+			return nil, err
+		}
+
+		taxonomy[slug] = slug
+		for _, alias := range aliases {
+			taxonomy[alias] = slug
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return taxonomy, nil
+}
+
+// GetAll returns every genre ordered by name. The taxonomy is small enough not to be paginated.
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `SELECT id, created_at, slug, name, aliases, version
+						FROM genres
+						ORDER BY name ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	genres := make([]*Genre, 0)
+
+	for rows.Next() {
+		var genre Genre
+
+		err = rows.Scan(
+			&genre.ID,
+			&genre.CreatedAt,
+			&genre.Slug,
+			&genre.Name,
+			pq.Array(&genre.Aliases),
+			&genre.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+func (m GenreModel) Get(id int64) (*Genre, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, slug, name, aliases, version
+						FROM genres
+						WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var genre Genre
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&genre.ID,
+		&genre.CreatedAt,
+		&genre.Slug,
+		&genre.Name,
+		pq.Array(&genre.Aliases),
+		&genre.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &genre, nil
+}
+
+// Insert adds a genre to the taxonomy. It returns ErrDuplicateGenre if its slug or one of its
+// aliases is already the slug or an alias of another genre.
+func (m GenreModel) Insert(genre *Genre) error {
+	query := `INSERT INTO genres (slug, name, aliases)
+						SELECT $1, $2, $3
+						WHERE NOT EXISTS (SELECT 1 FROM genres WHERE slug = ANY($4) OR aliases && $4)
+						RETURNING id, created_at, version`
+
+	names := append([]string{genre.Slug}, genre.Aliases...)
+	args := []any{genre.Slug, genre.Name, pq.Array(genre.Aliases), pq.Array(names)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&genre.ID, &genre.CreatedAt, &genre.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateGenre
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrDuplicateGenre
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update saves the name and the aliases of a genre, its slug being immutable. It returns
+// ErrDuplicateGenre if one of the aliases belongs to another genre.
+func (m GenreModel) Update(genre *Genre) error {
+	query := `UPDATE genres
+						SET name = $1, aliases = $2, version = version + 1
+						WHERE id = $3 AND version = $4
+						AND NOT EXISTS (SELECT 1 FROM genres g WHERE g.id <> $3 AND (g.slug = ANY($2) OR g.aliases && $2))
+						RETURNING version`
+
+	args := []any{genre.Name, pq.Array(genre.Aliases), genre.ID, genre.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&genre.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return m.updateError(genre.ID, genre.Aliases)
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateError tells why an update of a genre didn't affect any row: either one of its aliases
+// belongs to another genre, or the genre was modified or deleted in the meantime.
+func (m GenreModel) updateError(id int64, aliases []string) error {
+	query := `SELECT EXISTS (SELECT 1 FROM genres WHERE id <> $1 AND (slug = ANY($2) OR aliases && $2))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var duplicate bool
+
+	err := m.DB.QueryRowContext(ctx, query, id, pq.Array(aliases)).Scan(&duplicate)
+	if err != nil {
+		return err
+	}
+
+	if duplicate {
+		return ErrDuplicateGenre
+	}
+
+	return ErrEditConflict
+}
+
+// Delete removes a genre from the taxonomy. It returns ErrGenreInUse if any movie, including
+// the ones in the trash, still references it.
+func (m GenreModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `WITH target AS (
+							SELECT id, slug FROM genres WHERE id = $1
+						), deleted AS (
+							DELETE FROM genres
+							WHERE id IN (SELECT id FROM target)
+							AND NOT EXISTS (SELECT 1 FROM movies, target WHERE movies.genres @> ARRAY[target.slug])
+							RETURNING id
+						)
+						SELECT EXISTS (SELECT 1 FROM target), EXISTS (SELECT 1 FROM deleted)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var found, deleted bool
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&found, &deleted)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !found:
+		return ErrRecordNotFound
+	case !deleted:
+		return ErrGenreInUse
+	default:
+		return nil
+	}
+}
+
+// Merge merges the source genre into the target one, for the duplicates of a genre known
+// under several slugs. The movies of the source genre, including the ones in the trash, get
+// the target genre instead, as a new version recorded in their revisions on behalf of the
+// given user. The source genre is then deleted, target.Aliases, which must already hold the
+// slug and the aliases of the source genre, being saved in the same transaction. It returns
+// the number of movies updated, or ErrEditConflict if either genre was modified or deleted
+// since it was read.
+func (m GenreModel) Merge(source, target *Genre, userID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM genres WHERE id = $1 AND version = $2`, source.ID, source.Version)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected == 0 {
+		return 0, ErrEditConflict
+	}
+
+	query := `UPDATE genres
+						SET aliases = $1, version = version + 1
+						WHERE id = $2 AND version = $3
+						RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, pq.Array(target.Aliases), target.ID, target.Version).Scan(&target.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrEditConflict
+		default:
+			return 0, err
+		}
+	}
+
+	// Replace the source slug in place, dropping it instead if the movie already has the target
+	// genre, and record the change like any other update.
+	query = fmt.Sprintf(`WITH before AS (
+							SELECT id, title, year, runtime, genres FROM movies
+							WHERE genres @> ARRAY[$1]
+						), after AS (
+							UPDATE movies m
+							SET genres = ARRAY(
+								SELECT g.genre
+								FROM unnest(array_replace(m.genres, $1, $2)) WITH ORDINALITY AS g(genre, position)
+								GROUP BY g.genre
+								ORDER BY MIN(g.position)
+							), version = m.version + 1
+							WHERE m.genres @> ARRAY[$1]
+							RETURNING m.id, m.version, m.title, m.year, m.runtime, m.genres
+						), revision AS (
+							INSERT INTO movie_revisions (movie_id, version, action, user_id, before, after)
+							SELECT after.id, after.version, 'update', $3, %s, %s
+							FROM before INNER JOIN after ON after.id = before.id
+						)
+						SELECT COUNT(*) FROM after`, movieSnapshotJSON("before"), movieSnapshotJSON("after"))
+
+	var updated int64
+
+	err = tx.QueryRowContext(ctx, query, source.Slug, target.Slug, userID).Scan(&updated)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	m.onSearchChange.notify()
+
+	return updated, nil
+}
+
+// resolveGenres replaces the genres of a movie by the slugs they resolve to in the taxonomy,
+// and records an error for each of them which is unknown.
+func resolveGenres(v *validator.Validator, genres []string, taxonomy GenreTaxonomy) {
+	for i, genre := range genres {
+		slug, ok := taxonomy.Resolve(genre)
+		if !ok {
+			v.AddError("genres", fmt.Sprintf("unknown genre %q", genre))
+			continue
+		}
+		genres[i] = slug
+	}
+}
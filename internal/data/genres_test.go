@@ -0,0 +1,102 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestGenreSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "drama", want: "drama"},
+		{name: "Sci-Fi", want: "sci-fi"},
+		{name: "sci fi", want: "sci-fi"},
+		{name: "SCI_FI", want: "sci-fi"},
+		{name: "  Film   Noir  ", want: "film-noir"},
+		{name: "--80s--", want: "80s"},
+		{name: "Drame Psychologique!", want: "drame-psychologique"},
+		{name: "!?", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenreSlug(tt.name); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenreTaxonomyResolve(t *testing.T) {
+	taxonomy := GenreTaxonomy{"sci-fi": "sci-fi", "science-fiction": "sci-fi", "drama": "drama"}
+
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{name: "sci-fi", want: "sci-fi", ok: true},
+		{name: "Science Fiction", want: "sci-fi", ok: true},
+		{name: "DRAMA", want: "drama", ok: true},
+		{name: "western", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := taxonomy.Resolve(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %q, %t; want %q, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	t.Run("resolveGenres", func(t *testing.T) {
+		v := validator.New()
+		genres := []string{"Science Fiction", "western", "Drama"}
+
+		resolveGenres(v, genres, taxonomy)
+
+		if want := []string{"sci-fi", "western", "drama"}; !slices.Equal(genres, want) {
+			t.Errorf("got genres %v; want %v", genres, want)
+		}
+		if v.Errors["genres"] != `unknown genre "western"` {
+			t.Errorf("got errors %v; want western to be unknown", v.Errors)
+		}
+	})
+}
+
+func TestValidateGenre(t *testing.T) {
+	tests := []struct {
+		name  string
+		genre Genre
+		valid bool
+	}{
+		{name: "valid", genre: Genre{Slug: "sci-fi", Name: "Science Fiction", Aliases: []string{"Science Fiction", "scifi"}}, valid: true},
+		{name: "no aliases", genre: Genre{Slug: "drama", Name: "Drama", Aliases: []string{}}, valid: true},
+		{name: "missing aliases", genre: Genre{Slug: "drama", Name: "Drama"}, valid: false},
+		{name: "missing slug", genre: Genre{Name: "Drama", Aliases: []string{}}, valid: false},
+		{name: "slug not normalized", genre: Genre{Slug: "Sci Fi", Name: "Sci-Fi", Aliases: []string{}}, valid: false},
+		{name: "missing name", genre: Genre{Slug: "drama", Aliases: []string{}}, valid: false},
+		{name: "name too long", genre: Genre{Slug: "drama", Name: strings.Repeat("a", 101), Aliases: []string{}}, valid: false},
+		{name: "alias of the slug", genre: Genre{Slug: "sci-fi", Name: "Sci-Fi", Aliases: []string{"Sci Fi"}}, valid: false},
+		{name: "empty alias", genre: Genre{Slug: "drama", Name: "Drama", Aliases: []string{"!"}}, valid: false},
+		{name: "duplicate aliases", genre: Genre{Slug: "sci-fi", Name: "Sci-Fi", Aliases: []string{"scifi", "SCIFI"}}, valid: false},
+		{name: "too many aliases", genre: Genre{Slug: "drama", Name: "Drama", Aliases: strings.Fields(strings.Repeat("a ", 21))}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateGenre(v, &tt.genre)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
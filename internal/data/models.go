@@ -11,18 +11,48 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// A searchListener is called after the writes which may change the results of movie searches,
+// such as to invalidate the caches of these results, once they succeeded. It may be nil.
+type searchListener func()
+
+func (l searchListener) notify() {
+	if l != nil {
+		l()
+	}
+}
+
 type Models struct {
 	Movies      MovieModel
+	Revisions   MovieRevisionModel
 	Tokens      TokenModel
 	Users       UserModel
 	Permissions PermissionModel
+	Ratings     RatingModel
+	Reviews     ReviewModel
+	Watchlists  WatchlistModel
+	People      PersonModel
+	Credits     CreditModel
+	Genres      GenreModel
+	Titles      MovieTitleModel
+	Collections CollectionModel
 }
 
-func NewModels(db *sql.DB) Models {
+// NewModels returns the models backed by db. The onSearchChange function, if not nil, is
+// called after the writes which may change the results of movie searches.
+func NewModels(db *sql.DB, onSearchChange func()) Models {
 	return Models{
-		Movies:      MovieModel{DB: db},
+		Movies:      MovieModel{DB: db, onSearchChange: onSearchChange},
+		Revisions:   MovieRevisionModel{DB: db},
 		Tokens:      TokenModel{DB: db},
 		Users:       UserModel{DB: db},
 		Permissions: PermissionModel{DB: db},
+		Ratings:     RatingModel{DB: db},
+		Reviews:     ReviewModel{DB: db},
+		Watchlists:  WatchlistModel{DB: db},
+		People:      PersonModel{DB: db, onSearchChange: onSearchChange},
+		Credits:     CreditModel{DB: db, onSearchChange: onSearchChange},
+		Genres:      GenreModel{DB: db, onSearchChange: onSearchChange},
+		Titles:      MovieTitleModel{DB: db, onSearchChange: onSearchChange},
+		Collections: CollectionModel{DB: db, onSearchChange: onSearchChange},
 	}
 }
@@ -5,53 +5,265 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/lib/pq"
 	"greenlight.hichammou/internal/validator"
 )
 
 type Movie struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"` // the - tells the encoder to not show this field in the generated JSON
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`           // omitempty is used to tell the encoder to not show this field in the final JSON - if the value of this field is empty
-	Runtime   Runtime   `json:"runtime,omitempty,string"` // string is to show this field as a string in JSON
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
+	ID            int64            `json:"id"`
+	CreatedAt     time.Time        `json:"-"` // the - tells the encoder to not show this field in the generated JSON
+	Title         string           `json:"title"`
+	OriginalTitle string           `json:"original_title,omitempty"` // only set when the title may have been localized
+	Year          int32            `json:"year,omitempty"`           // omitempty is used to tell the encoder to not show this field in the final JSON - if the value of this field is empty
+	Runtime       Runtime          `json:"runtime,omitempty,string"` // string is to show this field as a string in JSON
+	Genres        []string         `json:"genres,omitempty"`
+	Version       int32            `json:"version"`
+	Rating        MovieRating      `json:"rating"`
+	Credits       []*Credit        `json:"credits,omitempty"`     // only loaded on request
+	Collection    *MovieCollection `json:"collection,omitempty"`  // only loaded when showing a single movie
+	DeletedAt     *time.Time       `json:"deleted_at,omitempty"`  // only set for movies listed from the trash
+	MatchScore    float64          `json:"match_score,omitempty"` // only set for movies listed with a title search
+	PosterURL     PosterURL        `json:"poster_url,omitempty"`
+	ExternalIDs   ExternalIDs      `json:"external_ids,omitempty"`
+	Similarity    float64          `json:"similarity,omitempty"` // only set for movies listed as similar to another one
+}
+
+// MovieRating holds the aggregated user ratings of a movie. It's maintained by
+// RatingModel.Upsert.
+type MovieRating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
 }
 
 type MovieModel struct {
-	DB *sql.DB
+	DB             *sql.DB
+	onSearchChange searchListener
 }
 
-func (m MovieModel) Insert(movie *Movie) error {
-	query := `
-						INSERT INTO movies (title, year, runtime, genres)
-						VALUES ($1, $2, $3, $4)
-						RETURNING id, created_at, version
-	`
+// MovieSearch holds the query string parameters used to filter lists of movies.
+type MovieSearch struct {
+	Title         string
+	Genres        []string
+	ExcludeGenres []string
+	YearMin       int32
+	YearMax       int32
+	RuntimeMin    int32
+	RuntimeMax    int32
+	PersonID      int64
+	CollectionID  int64
+}
+
+// movieSearchCondition is the WHERE clause shared by every query that filters movies with a
+// MovieSearch. Its parameters are the values returned by MovieSearch.args(), starting at $1.
+// Filters which are not set are bound to a neutral value rather than left out of the query.
+//
+// A title matches the search if it contains words starting with every word searched for
+// ($9 is the prefix query built from the search), or if it is close enough to the search
+// according to pg_trgm to tolerate typos. The localized titles of the movie are searched too.
+const movieSearchCondition = `($1 = '' OR to_tsvector('simple', title) @@ to_tsquery('simple', $9) OR $1 <% title -- the @@ symbol in pg is 'matches'
+							OR id IN (
+								SELECT mt.movie_id FROM movie_titles mt
+								WHERE to_tsvector('simple', mt.title) @@ to_tsquery('simple', $9) OR $1 <% mt.title
+							))
+						AND (genres @> $2 OR $2 = '{}')
+						AND ($3 = 0 OR id IN (SELECT movie_id FROM movie_credits WHERE person_id = $3))
+						AND NOT (genres && $4)
+						AND ($5 = 0 OR year >= $5)
+						AND ($6 = 0 OR year <= $6)
+						AND ($7 = 0 OR runtime >= $7)
+						AND ($8 = 0 OR runtime <= $8)
+						AND ($10 = 0 OR id IN (SELECT movie_id FROM collection_movies WHERE collection_id = $10))`
+
+// args returns the parameters of movieSearchCondition. Queries bind their own parameters
+// after these ones.
+func (s MovieSearch) args() []any {
+	return []any{
+		s.Title,
+		pq.Array(s.Genres),
+		s.PersonID,
+		pq.Array(s.ExcludeGenres),
+		s.YearMin,
+		s.YearMax,
+		s.RuntimeMin,
+		s.RuntimeMax,
+		prefixQuery(s.Title),
+		s.CollectionID,
+	}
+}
+
+// movieRelevance joins the relevance of each movie to the title search of
+// movieSearchCondition, to the queries which can sort by relevance. It is 0 without a search.
+const movieRelevance = `CROSS JOIN LATERAL (
+							SELECT CASE WHEN $1 = '' THEN 0
+							ELSE GREATEST(
+								ts_rank(to_tsvector('simple', movies.title), to_tsquery('simple', $9)),
+								word_similarity($1, movies.title),
+								(SELECT MAX(word_similarity($1, mt.title)) FROM movie_titles mt WHERE mt.movie_id = movies.id)
+							)
+							END AS relevance
+						) r`
+
+// prefixQuery turns a title search into a tsquery matching the titles containing words which
+// start with each of the words searched for, e.g. "god fath" becomes "god:* & fath:*".
+func prefixQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i := range words {
+		words[i] += ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	for _, facet := range facets {
+		v.Check(validator.In(facet, MovieFacetsSafelist...), "facets", "invalid facet value")
+	}
+	v.Check(validator.Unique(facets), "facets", "must not contain duplicate values")
+}
+
+func ValidateMovieSearch(v *validator.Validator, s MovieSearch) {
+	v.Check(s.PersonID >= 0, "person_id", "must be a positive integer")
+	v.Check(s.CollectionID >= 0, "collection_id", "must be a positive integer")
+
+	// The bounds match the ones enforced by ValidateMovie, a zero value meaning no bound.
+	if s.YearMin != 0 {
+		v.Check(s.YearMin >= 1888, "year_min", "must be greater than 1888")
+		v.Check(s.YearMin <= int32(time.Now().Year()), "year_min", "must not be in the future")
+	}
+	if s.YearMax != 0 {
+		v.Check(s.YearMax >= 1888, "year_max", "must be greater than 1888")
+		v.Check(s.YearMax <= int32(time.Now().Year()), "year_max", "must not be in the future")
+	}
+	v.Check(s.YearMax == 0 || s.YearMin <= s.YearMax, "year_min", "must not be greater than year_max")
+
+	v.Check(s.RuntimeMin >= 0, "runtime_min", "must be a positive integer")
+	v.Check(s.RuntimeMax >= 0, "runtime_max", "must be a positive integer")
+	v.Check(s.RuntimeMax == 0 || s.RuntimeMin <= s.RuntimeMax, "runtime_min", "must not be greater than runtime_max")
+
+	v.Check(len(s.ExcludeGenres) <= 5, "exclude_genres", "must not contain more than 5 genres")
+	v.Check(validator.Unique(s.ExcludeGenres), "exclude_genres", "must not contain duplicate values")
+}
+
+// movieInsertQuery inserts a movie and records its first revision in the same statement.
+// The user responsible for the change is bound to $5.
+var movieInsertQuery = fmt.Sprintf(`
+						WITH inserted AS (
+							INSERT INTO movies (title, year, runtime, genres)
+							VALUES ($1, $2, $3, $4)
+							RETURNING id, created_at, version, title, year, runtime, genres
+						), revision AS (
+							INSERT INTO movie_revisions (movie_id, version, action, user_id, after)
+							SELECT id, version, 'insert', $5, %s FROM inserted
+						)
+						SELECT id, created_at, version FROM inserted
+	`, movieSnapshotJSON("inserted"))
+
+// Insert stores a new movie on behalf of the given user, along with its external identifiers.
+// It returns ErrDuplicateExternalID if one of them already belongs to another movie.
+func (m MovieModel) Insert(movie *Movie, userID int64) error {
 	// Create an args slice containing the values for the placeholder parameters from the movie struct.
-	args := []any{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+	args := []any{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), userID}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, movieInsertQuery, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	if err != nil {
+		return err
+	}
+
+	err = replaceExternalIDs(ctx, tx, movie.ID, movie.ExternalIDs)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
 }
 
-func (m MovieModel) List(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
-	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), id, created_at, title, year, runtime, genres, version 
+// InsertBatch inserts all the given movies inside a single transaction, so either every
+// movie of the batch is stored or none of them is. The ID, CreatedAt and Version fields
+// of each movie are populated on success.
+func (m MovieModel) InsertBatch(movies []*Movie, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, movieInsertQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, movie := range movies {
+		args := []any{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), userID}
+
+		err = stmt.QueryRowContext(ctx, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// List returns a page of the movies matching the search. Only the columns backing the given
+// fields are selected, all of them if fields is empty.
+func (m MovieModel) List(search MovieSearch, fields []string, filters Filters) ([]*Movie, Metadata, error) {
+	args := search.args()
+
+	columns, dest := movieFieldset(fields)
+
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), %s, relevance
 						FROM movies
-						WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '') -- add full test search. the @@ symbol in pg is 'matches'  
-						AND (genres @> $2 OR $2 = '{}')
+						%s
+						WHERE deleted_at IS NULL
+						AND %s
 						ORDER BY %s %s ,id ASC
-						LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+						LIMIT $%d OFFSET $%d`, columns, movieRelevance, movieSearchCondition, filters.sortColumn(), filters.sortDirection(), len(args)+1, len(args)+2)
+
+	args = append(args, filters.limit(), filters.offset())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, title, pq.Array(genres), filters.limit(), filters.offset())
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -61,11 +273,297 @@ func (m MovieModel) List(title string, genres []string, filters Filters) ([]*Mov
 	totalRecords := 0
 	movies := make([]*Movie, 0)
 
+	for rows.Next() {
+		var movie Movie
+
+		err = rows.Scan(append(append([]any{&totalRecords}, dest(&movie)...), &movie.MatchScore)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	if rows.Err() != nil {
+		return nil, Metadata{}, err
+	}
+	return movies, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// FacetCount is the number of movies sharing a value of a facet.
+type FacetCount struct {
+	Value any `json:"value"`
+	Count int `json:"count"`
+}
+
+// movieFacets maps the name of each facet supported by MovieModel.Facets to the lateral
+// expression producing its values and the ordering of its counts.
+var movieFacets = map[string]struct {
+	values  string
+	orderBy string
+}{
+	"genres": {values: "unnest(genres)", orderBy: "count DESC, value ASC"},
+	"year":   {values: "(SELECT year)", orderBy: "value ASC"},
+	"decade": {values: "(SELECT year / 10 * 10)", orderBy: "value ASC"},
+}
+
+// MovieFacetsSafelist lists the facets accepted by MovieModel.Facets.
+var MovieFacetsSafelist = []string{"genres", "year", "decade"}
+
+// Facets counts, for each of the requested facets, the movies matching the search per value
+// of the facet. The counts are computed against the same WHERE clause as List, so the genres
+// facet can make use of the GIN index on genres.
+func (m MovieModel) Facets(search MovieSearch, facets []string) (map[string][]FacetCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result := make(map[string][]FacetCount, len(facets))
+
+	for _, name := range facets {
+		facet, ok := movieFacets[name]
+		if !ok {
+			panic("unsafe facet parameter: " + name)
+		}
+
+		query := fmt.Sprintf(`SELECT facet.value, COUNT(*) AS count
+						FROM movies
+						CROSS JOIN LATERAL %s AS facet(value)
+						WHERE deleted_at IS NULL
+						AND %s
+						GROUP BY facet.value
+						ORDER BY %s`, facet.values, movieSearchCondition, facet.orderBy)
+
+		rows, err := m.DB.QueryContext(ctx, query, search.args()...)
+		if err != nil {
+			return nil, err
+		}
+
+		counts := make([]FacetCount, 0)
+
+		for rows.Next() {
+			var count FacetCount
+
+			err = rows.Scan(&count.Value, &count.Count)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts = append(counts, count)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		result[name] = counts
+	}
+
+	return result, nil
+}
+
+// ListAfter returns the page of movies that follows the given cursor (or the first page if
+// the cursor is nil) using keyset pagination. Unlike List, it doesn't count the matching
+// records and isn't affected by movies inserted while the client is paging through results.
+func (m MovieModel) ListAfter(search MovieSearch, fields []string, cursor *Cursor, filters Filters) ([]*Movie, CursorMetadata, error) {
+	// Fetch one more record than requested to know whether there is a next page.
+	args := append(search.args(), filters.limit()+1)
+
+	after := "TRUE"
+	if cursor != nil {
+		value, err := parseSortValue(filters.sortColumn(), cursor.Value)
+		if err != nil {
+			return nil, CursorMetadata{}, err
+		}
+
+		after = filters.keysetCondition(fmt.Sprintf("$%d", len(args)+1), fmt.Sprintf("$%d", len(args)+2))
+		args = append(args, value, cursor.ID)
+	}
+
+	// The sort column is needed to build the next cursor, even if it isn't a requested field.
+	columns, dest := movieFieldset(fields, filters.sortColumn())
+
+	query := fmt.Sprintf(`SELECT %s, relevance
+						FROM movies
+						%s
+						WHERE deleted_at IS NULL
+						AND %s
+						AND %s
+						ORDER BY %s %s, id ASC
+						LIMIT $%d`, columns, movieRelevance, movieSearchCondition, after, filters.sortColumn(), filters.sortDirection(), len(search.args())+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, CursorMetadata{}, err
+	}
+
+	defer rows.Close()
+
+	movies := make([]*Movie, 0)
+
+	for rows.Next() {
+		var movie Movie
+
+		err = rows.Scan(append(dest(&movie), &movie.MatchScore)...)
+		if err != nil {
+			return nil, CursorMetadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, CursorMetadata{}, err
+	}
+
+	metadata := CursorMetadata{PageSize: filters.PageSize}
+
+	if len(movies) > filters.limit() {
+		movies = movies[:filters.limit()]
+
+		last := movies[len(movies)-1]
+		metadata.NextCursor = Cursor{
+			Sort:  filters.Sort,
+			Value: last.sortValue(filters.sortColumn()),
+			ID:    last.ID,
+		}.Encode()
+	}
+
+	return movies, metadata, nil
+}
+
+// sortValue returns the value of the given sort column for the movie, formatted so that it
+// can be parsed back by parseSortValue. Floats are formatted with the shortest representation
+// which parses back into the same value, so that pages don't skip or repeat rows at ties.
+func (movie *Movie) sortValue(column string) string {
+	switch column {
+	case "title":
+		return movie.Title
+	case "year":
+		return strconv.Itoa(int(movie.Year))
+	case "runtime":
+		return strconv.Itoa(int(movie.Runtime))
+	case "rating":
+		return strconv.FormatFloat(movie.Rating.Average, 'g', -1, 64)
+	case "relevance":
+		return strconv.FormatFloat(movie.MatchScore, 'g', -1, 64)
+	default:
+		return strconv.FormatInt(movie.ID, 10)
+	}
+}
+
+// parseSortValue parses a value formatted by sortValue into the type of the sort column. It
+// returns ErrInvalidCursor if the value doesn't have that type.
+func parseSortValue(column, value string) (any, error) {
+	switch column {
+	case "title":
+		if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+			return nil, ErrInvalidCursor
+		}
+		return value, nil
+	case "year", "runtime":
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return i, nil
+	case "rating", "relevance":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, ErrInvalidCursor
+		}
+		return f, nil
+	default:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return i, nil
+	}
+}
+
+// MovieSuggestion is the short form of a movie returned by the title autocomplete.
+type MovieSuggestion struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+// Suggest returns up to limit movies whose title completes the given prefix. Titles starting
+// with the prefix come first, then the ones containing words starting with it and finally the
+// ones which only match it approximately, each group ranked by trigram word similarity.
+func (m MovieModel) Suggest(prefix string, limit int) ([]*MovieSuggestion, error) {
+	query := `SELECT id, title, year
+						FROM movies
+						WHERE deleted_at IS NULL
+						AND (title ILIKE $2 OR to_tsvector('simple', title) @@ to_tsquery('simple', $3) OR $1 <% title)
+						ORDER BY title ILIKE $2 DESC, to_tsvector('simple', title) @@ to_tsquery('simple', $3) DESC,
+						word_similarity($1, title) DESC, title ASC
+						LIMIT $4`
+
+	// Escape the LIKE wildcards so that they match literally.
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, prefix, pattern, prefixQuery(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := make([]*MovieSuggestion, 0, limit)
+
+	for rows.Next() {
+		var suggestion MovieSuggestion
+
+		err = rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// Export calls fn for every movie matching the search, ordered by id. Rows
+// are streamed from the database cursor rather than collected into a slice, and the whole
+// scan runs inside a single read-only REPEATABLE READ transaction so the caller sees a
+// consistent snapshot even while movies are being written. The scan is canceled when ctx is.
+func (m MovieModel) Export(ctx context.Context, search MovieSearch, fn func(*Movie) error) error {
+	query := fmt.Sprintf(`SELECT id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key
+						FROM movies
+						WHERE deleted_at IS NULL
+						AND %s
+						ORDER BY id ASC`, movieSearchCondition)
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	// The transaction never writes, so rolling it back is enough to release the snapshot.
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, search.args()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var movie Movie
 
 		err = rows.Scan(
-			&totalRecords,
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.Title,
@@ -73,17 +571,21 @@ func (m MovieModel) List(title string, genres []string, filters Filters) ([]*Mov
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
 			&movie.Version,
+			&movie.Rating.Average,
+			&movie.Rating.Count,
+			&movie.PosterURL,
 		)
 		if err != nil {
-			return nil, Metadata{}, err
+			return err
 		}
-		movies = append(movies, &movie)
-	}
 
-	if rows.Err() != nil {
-		return nil, Metadata{}, err
+		err = fn(&movie)
+		if err != nil {
+			return err
+		}
 	}
-	return movies, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+
+	return rows.Err()
 }
 
 func (m MovieModel) Get(id int64) (*Movie, error) {
@@ -93,9 +595,9 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 
 	movie := &Movie{}
 	query := `
-		SELECT id, created_at, title, year, runtime, genres, version
+		SELECT id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key, ` + externalIDsColumn + `
 		FROM movies
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	// Create a context with a 3 seconds timeout. (to cancel the sql query if did not complete before that time)
@@ -111,6 +613,10 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 		&movie.Year,
 		&movie.Runtime,
 		pq.Array(&movie.Genres), &movie.Version,
+		&movie.Rating.Average,
+		&movie.Rating.Count,
+		&movie.PosterURL,
+		&movie.ExternalIDs,
 	)
 
 	if err != nil {
@@ -125,11 +631,104 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	return movie, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
-	query := `UPDATE movies 
-						SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-						WHERE id = $5 AND version = $6
-						RETURNING version`
+// GetMany returns the movies with the given ids in a single query, keyed by id. The ids of
+// the movies which don't exist or are in the trash are missing from the map.
+func (m MovieModel) GetMany(ids []int64) (map[int64]*Movie, error) {
+	query := `SELECT id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key
+						FROM movies
+						WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movies := make(map[int64]*Movie, len(ids))
+
+	for rows.Next() {
+		var movie Movie
+
+		err = rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.Rating.Average,
+			&movie.Rating.Count,
+			&movie.PosterURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies[movie.ID] = &movie
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// GetFields is like Get, but only selects the columns backing the given fields. The version,
+// poster and rating are always selected, as the entity tag of the movie is computed from them.
+func (m MovieModel) GetFields(id int64, fields []string) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	columns, dest := movieFieldset(fields, "version", "poster_url", "rating")
+
+	query := fmt.Sprintf(`SELECT %s
+						FROM movies
+						WHERE id = $1 AND deleted_at IS NULL`, columns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var movie Movie
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(dest(&movie)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &movie, nil
+}
+
+// Update saves the changes made to a movie on behalf of the given user, and records the
+// previous and new values as a revision. The external identifiers of the movie are replaced
+// as well, unless they're nil. It returns ErrDuplicateExternalID if one of them already
+// belongs to another movie.
+func (m MovieModel) Update(movie *Movie, userID int64) error {
+	// All the sub-statements of the query see the same snapshot, so "before" holds the values
+	// the movie had before the update.
+	query := fmt.Sprintf(`WITH before AS (
+							SELECT id, title, year, runtime, genres FROM movies
+							WHERE id = $5 AND version = $6 AND deleted_at IS NULL
+						), after AS (
+							UPDATE movies
+							SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
+							WHERE id = $5 AND version = $6 AND deleted_at IS NULL
+							RETURNING id, version, title, year, runtime, genres
+						), revision AS (
+							INSERT INTO movie_revisions (movie_id, version, action, user_id, before, after)
+							SELECT after.id, after.version, 'update', $7, %s, %s FROM before, after
+						)
+						SELECT version FROM after`, movieSnapshotJSON("before"), movieSnapshotJSON("after"))
 
 	args := []interface{}{
 		movie.Title,
@@ -138,15 +737,22 @@ func (m MovieModel) Update(movie *Movie) error {
 		pq.Array(movie.Genres),
 		movie.ID,
 		movie.Version,
+		userID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// Execute the SQL query. If no matching row could be found, we know the movie
 	// version has changed (or the record has been deleted) and we return our custom
 	// ErrEditConflict error.
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -155,24 +761,216 @@ func (m MovieModel) Update(movie *Movie) error {
 			return err
 		}
 	}
+
+	if movie.ExternalIDs != nil {
+		err = replaceExternalIDs(ctx, tx, movie.ID, movie.ExternalIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.onSearchChange.notify()
+
 	return nil
 }
 
-func (m MovieModel) Delete(id int64) error {
+// Delete moves a movie to the trash by setting its deleted_at timestamp. The row is kept
+// until it's restored or permanently removed by Purge. Like Update, it only deletes the given
+// version of the movie, and returns ErrEditConflict if the movie has changed (or has been
+// deleted) since it was read.
+func (m MovieModel) Delete(id int64, version int32, userID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
-	query := `DELETE FROM movies WHERE id = $1`
+	query := fmt.Sprintf(`WITH deleted AS (
+							UPDATE movies
+							SET deleted_at = NOW(), version = version + 1
+							WHERE id = $1 AND version = $3 AND deleted_at IS NULL
+							RETURNING id, version, title, year, runtime, genres
+						)
+						INSERT INTO movie_revisions (movie_id, version, action, user_id, before, after)
+						SELECT id, version, 'delete', $2, %[1]s, %[1]s FROM deleted`, movieSnapshotJSON("deleted"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// ListDeleted returns the movies that are currently in the trash.
+func (m MovieModel) ListDeleted(search MovieSearch, filters Filters) ([]*Movie, Metadata, error) {
+	args := search.args()
+
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key, deleted_at
+						FROM movies
+						WHERE deleted_at IS NOT NULL
+						AND %s
+						ORDER BY %s %s, id ASC
+						LIMIT $%d OFFSET $%d`, movieSearchCondition, filters.sortColumn(), filters.sortDirection(), len(args)+1, len(args)+2)
+
+	args = append(args, filters.limit(), filters.offset())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, id)
-	return err
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := make([]*Movie, 0)
+
+	for rows.Next() {
+		var movie Movie
+
+		err = rows.Scan(
+			&totalRecords,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.Rating.Average,
+			&movie.Rating.Count,
+			&movie.PosterURL,
+			&movie.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	return movies, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
+// Restore takes a movie out of the trash. It returns ErrRecordNotFound if there is no deleted
+// movie with the given id.
+func (m MovieModel) Restore(id int64, userID int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := fmt.Sprintf(`WITH restored AS (
+							UPDATE movies
+							SET deleted_at = NULL, version = version + 1
+							WHERE id = $1 AND deleted_at IS NOT NULL
+							RETURNING id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key
+						), revision AS (
+							INSERT INTO movie_revisions (movie_id, version, action, user_id, before, after)
+							SELECT id, version, 'restore', $2, %[1]s, %[1]s FROM restored
+						)
+						SELECT id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key FROM restored`, movieSnapshotJSON("restored"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var movie Movie
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		pq.Array(&movie.Genres),
+		&movie.Version,
+		&movie.Rating.Average,
+		&movie.Rating.Count,
+		&movie.PosterURL,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	m.onSearchChange.notify()
+
+	return &movie, nil
+}
+
+// Purge permanently removes the movies that were moved to the trash before the given time.
+// It returns the number of removed rows and the storage keys of their posters, which the
+// caller is responsible for deleting.
+func (m MovieModel) Purge(deletedBefore time.Time) (int64, []string, error) {
+	query := `DELETE FROM movies WHERE deleted_at < $1 RETURNING poster_key`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer rows.Close()
+
+	var (
+		purged  int64
+		posters []string
+	)
+
+	for rows.Next() {
+		var poster sql.NullString
+
+		err = rows.Scan(&poster)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		purged++
+		if poster.Valid {
+			posters = append(posters, poster.String)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	if purged > 0 {
+		m.onSearchChange.notify()
+	}
+
+	return purged, posters, nil
+}
+
+// ValidateMovie checks a movie, resolving its genres to the slugs of the taxonomy on the way.
+func ValidateMovie(v *validator.Validator, movie *Movie, genres GenreTaxonomy) {
 	v.Check(movie.Title != "", "title", "must be provided")
 	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
 
@@ -186,5 +984,10 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(movie.Genres != nil, "genres", "must be provided")
 	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
 	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
+
+	// Resolve the genres before checking for duplicates, as two aliases may name the same genre.
+	resolveGenres(v, movie.Genres, genres)
 	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+
+	ValidateExternalIDs(v, movie.ExternalIDs)
 }
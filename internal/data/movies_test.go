@@ -0,0 +1,117 @@
+package data
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestMovieModelInvalidIDs(t *testing.T) {
+	// The ids are checked before the database is used, so a model without one is enough.
+	m := MovieModel{}
+
+	tests := []struct {
+		name string
+		call func(id int64) error
+	}{
+		{name: "Get", call: func(id int64) error { _, err := m.Get(id); return err }},
+		{name: "Delete", call: func(id int64) error { return m.Delete(id, 1, 1) }},
+		{name: "Restore", call: func(id int64) error { _, err := m.Restore(id, 1); return err }},
+	}
+
+	for _, tt := range tests {
+		for _, id := range []int64{0, -1} {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := tt.call(id); !errors.Is(err, ErrRecordNotFound) {
+					t.Errorf("got %v for id %d; want ErrRecordNotFound", err, id)
+				}
+			})
+		}
+	}
+}
+
+func TestValidateFacets(t *testing.T) {
+	tests := []struct {
+		name   string
+		facets []string
+		valid  bool
+	}{
+		{name: "no facets", facets: nil, valid: true},
+		{name: "every facet", facets: []string{"genres", "year", "decade"}, valid: true},
+		{name: "unknown facet", facets: []string{"genres", "runtime"}, valid: false},
+		{name: "duplicate facet", facets: []string{"year", "year"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateFacets(v, tt.facets)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateMovieSearch(t *testing.T) {
+	nextYear := int32(time.Now().Year() + 1)
+
+	tests := []struct {
+		name   string
+		search MovieSearch
+		want   []string
+	}{
+		{name: "no filters", search: MovieSearch{}},
+		{name: "ranges", search: MovieSearch{YearMin: 1990, YearMax: 1999, RuntimeMin: 90, RuntimeMax: 120}},
+		{name: "open ranges", search: MovieSearch{YearMin: 2000, RuntimeMax: 90}},
+		{name: "single year", search: MovieSearch{YearMin: 1994, YearMax: 1994}},
+		{name: "year too old", search: MovieSearch{YearMin: 1887, YearMax: 1800}, want: []string{"year_max", "year_min"}},
+		{name: "year in the future", search: MovieSearch{YearMax: nextYear}, want: []string{"year_max"}},
+		{name: "inverted years", search: MovieSearch{YearMin: 2000, YearMax: 1990}, want: []string{"year_min"}},
+		{name: "negative runtimes", search: MovieSearch{RuntimeMin: -1, RuntimeMax: -1}, want: []string{"runtime_max", "runtime_min"}},
+		{name: "inverted runtimes", search: MovieSearch{RuntimeMin: 120, RuntimeMax: 90}, want: []string{"runtime_min"}},
+		{name: "negative ids", search: MovieSearch{PersonID: -1, CollectionID: -1}, want: []string{"collection_id", "person_id"}},
+		{name: "too many excluded genres", search: MovieSearch{ExcludeGenres: []string{"a", "b", "c", "d", "e", "f"}}, want: []string{"exclude_genres"}},
+		{name: "duplicate excluded genres", search: MovieSearch{ExcludeGenres: []string{"drama", "drama"}}, want: []string{"exclude_genres"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateMovieSearch(v, tt.search)
+
+			if got := slices.Sorted(maps.Keys(v.Errors)); !slices.Equal(got, tt.want) {
+				t.Errorf("got errors %v; want errors for %v", v.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixQuery(t *testing.T) {
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{search: "", want: ""},
+		{search: "god", want: "god:*"},
+		{search: "God Fath", want: "god:* & fath:*"},
+		{search: "  star   wars ", want: "star:* & wars:*"},
+		{search: "Ocean's 11", want: "ocean:* & s:* & 11:*"},
+		{search: "Amélie", want: "amélie:*"},
+		{search: "a & b | !c:*", want: "a:* & b:* & c:*"},
+		{search: "()<->", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			if got := prefixQuery(tt.search); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+var (
+	ErrUnknownPerson = errors.New("unknown person")
+)
+
+// Define the roles a person can be credited for.
+const (
+	RoleDirector = "director"
+	RoleWriter   = "writer"
+	RoleCast     = "cast"
+)
+
+type Person struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	BirthYear int32     `json:"birth_year,omitempty"`
+	Version   int32     `json:"version"`
+}
+
+// Credit links a person to a movie with a role. BillingOrder ranks the credits of a movie,
+// lowest first.
+type Credit struct {
+	PersonID     int64  `json:"person_id"`
+	Name         string `json:"name"`
+	Role         string `json:"role"`
+	Character    string `json:"character,omitempty"`
+	BillingOrder int    `json:"billing_order"`
+}
+
+type PersonModel struct {
+	DB             *sql.DB
+	onSearchChange searchListener
+}
+
+type CreditModel struct {
+	DB             *sql.DB
+	onSearchChange searchListener
+}
+
+func ValidatePerson(v *validator.Validator, person *Person) {
+	v.Check(person.Name != "", "name", "must be provided")
+	v.Check(len(person.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	if person.BirthYear != 0 {
+		v.Check(person.BirthYear >= 1800, "birth_year", "must be greater than 1800")
+		v.Check(person.BirthYear <= int32(time.Now().Year()), "birth_year", "must not be in the future")
+	}
+}
+
+func ValidateCredits(v *validator.Validator, credits []*Credit) {
+	v.Check(len(credits) <= 500, "credits", "must not contain more than 500 credits")
+
+	seen := make(map[string]bool)
+
+	for i, credit := range credits {
+		key := fmt.Sprintf("credits[%d]", i)
+
+		v.Check(credit.PersonID > 0, key+".person_id", "must be provided")
+		v.Check(validator.In(credit.Role, RoleDirector, RoleWriter, RoleCast), key+".role", "must be one of director, writer, cast")
+		v.Check(len(credit.Character) <= 500, key+".character", "must not be more than 500 bytes long")
+		v.Check(credit.Character == "" || credit.Role == RoleCast, key+".character", "must only be provided for the cast")
+		v.Check(credit.BillingOrder >= 0, key+".billing_order", "must not be negative")
+
+		// A person can hold several roles in a movie, but each role only once.
+		role := fmt.Sprintf("%d:%s", credit.PersonID, credit.Role)
+		v.Check(!seen[role], key, "duplicate person and role")
+		seen[role] = true
+	}
+}
+
+func (m PersonModel) Insert(person *Person) error {
+	query := `INSERT INTO people (name, birth_year)
+						VALUES ($1, NULLIF($2, 0))
+						RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, person.Name, person.BirthYear).Scan(&person.ID, &person.CreatedAt, &person.Version)
+}
+
+func (m PersonModel) Get(id int64) (*Person, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, name, COALESCE(birth_year, 0), version
+						FROM people
+						WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var person Person
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&person.ID,
+		&person.CreatedAt,
+		&person.Name,
+		&person.BirthYear,
+		&person.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+func (m PersonModel) List(name string, filters Filters) ([]*Person, Metadata, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), id, created_at, name, COALESCE(birth_year, 0), version
+						FROM people
+						WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+						ORDER BY %s %s, id ASC
+						LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	people := make([]*Person, 0)
+
+	for rows.Next() {
+		var person Person
+
+		err = rows.Scan(
+			&totalRecords,
+			&person.ID,
+			&person.CreatedAt,
+			&person.Name,
+			&person.BirthYear,
+			&person.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		people = append(people, &person)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return people, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (m PersonModel) Update(person *Person) error {
+	query := `UPDATE people
+						SET name = $1, birth_year = NULLIF($2, 0), version = version + 1
+						WHERE id = $3 AND version = $4
+						RETURNING version`
+
+	args := []any{person.Name, person.BirthYear, person.ID, person.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a person along with all their credits.
+func (m PersonModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM people WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// GetAllForMovie returns the credits of a movie in billing order.
+func (m CreditModel) GetAllForMovie(movieID int64) ([]*Credit, error) {
+	query := `SELECT mc.person_id, p.name, mc.role, mc.character, mc.billing_order
+						FROM movie_credits mc
+						INNER JOIN people p ON p.id = mc.person_id
+						WHERE mc.movie_id = $1
+						ORDER BY mc.billing_order ASC, mc.role ASC, p.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := make([]*Credit, 0)
+
+	for rows.Next() {
+		var credit Credit
+
+		err = rows.Scan(
+			&credit.PersonID,
+			&credit.Name,
+			&credit.Role,
+			&credit.Character,
+			&credit.BillingOrder,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// ReplaceForMovie replaces all the credits of a movie in a single transaction. It returns
+// ErrUnknownPerson if one of the credits references a person that doesn't exist.
+func (m CreditModel) ReplaceForMovie(movieID int64, credits []*Credit) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM movie_credits WHERE movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO movie_credits (movie_id, person_id, role, character, billing_order)
+						VALUES ($1, $2, $3, $4, $5)
+						RETURNING (SELECT name FROM people WHERE id = $2)`
+
+	for _, credit := range credits {
+		args := []any{movieID, credit.PersonID, credit.Role, credit.Character, credit.BillingOrder}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&credit.Name)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_person_id_fkey"`:
+				return ErrUnknownPerson
+			default:
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
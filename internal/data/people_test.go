@@ -0,0 +1,97 @@
+package data
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestValidatePerson(t *testing.T) {
+	tests := []struct {
+		name   string
+		person Person
+		valid  bool
+	}{
+		{name: "name only", person: Person{Name: "Michael Curtiz"}, valid: true},
+		{name: "birth year", person: Person{Name: "Michael Curtiz", BirthYear: 1886}, valid: true},
+		{name: "no name", person: Person{BirthYear: 1886}, valid: false},
+		{name: "too long name", person: Person{Name: strings.Repeat("a", 501)}, valid: false},
+		{name: "too early birth year", person: Person{Name: "Someone", BirthYear: 1799}, valid: false},
+		{name: "future birth year", person: Person{Name: "Someone", BirthYear: int32(time.Now().Year() + 1)}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidatePerson(v, &tt.person)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateCredits(t *testing.T) {
+	tests := []struct {
+		name    string
+		credits []*Credit
+		want    []string
+	}{
+		{
+			name: "valid credits",
+			credits: []*Credit{
+				{PersonID: 1, Role: RoleDirector},
+				{PersonID: 1, Role: RoleWriter},
+				{PersonID: 2, Role: RoleCast, Character: "Rick Blaine", BillingOrder: 1},
+			},
+		},
+		{name: "no credits", credits: []*Credit{}},
+		{
+			name:    "missing person and unknown role",
+			credits: []*Credit{{Role: RoleCast}, {PersonID: 2, Role: "producer"}},
+			want:    []string{"credits[0].person_id", "credits[1].role"},
+		},
+		{
+			name:    "character of a director",
+			credits: []*Credit{{PersonID: 1, Role: RoleDirector, Character: "Himself"}},
+			want:    []string{"credits[0].character"},
+		},
+		{
+			name:    "negative billing order",
+			credits: []*Credit{{PersonID: 1, Role: RoleCast, BillingOrder: -1}},
+			want:    []string{"credits[0].billing_order"},
+		},
+		{
+			name:    "duplicate person and role",
+			credits: []*Credit{{PersonID: 1, Role: RoleCast, Character: "Rick"}, {PersonID: 1, Role: RoleCast, Character: "Ilsa"}},
+			want:    []string{"credits[1]"},
+		},
+		{
+			name: "too many credits",
+			credits: func() []*Credit {
+				c := make([]*Credit, 501)
+				for i := range c {
+					c[i] = &Credit{PersonID: int64(i + 1), Role: RoleCast}
+				}
+				return c
+			}(),
+			want: []string{"credits"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCredits(v, tt.credits)
+
+			if got := slices.Sorted(maps.Keys(v.Errors)); !slices.Equal(got, tt.want) {
+				t.Errorf("got errors %v; want errors for %v", v.Errors, tt.want)
+			}
+		})
+	}
+}
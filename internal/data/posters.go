@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PosterURL is the URL a movie poster is served from. It is scanned from the storage key of the
+// poster, a NULL key meaning the movie has no poster.
+type PosterURL string
+
+func (u *PosterURL) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*u = ""
+	case []byte:
+		*u = PosterURL("/v1/posters/" + string(v))
+	case string:
+		*u = PosterURL("/v1/posters/" + v)
+	default:
+		return fmt.Errorf("unsupported poster key type %T", src)
+	}
+
+	return nil
+}
+
+// SetPoster replaces the storage key of the poster of a movie and returns the previous one,
+// which is empty if the movie had no poster. It returns ErrRecordNotFound if the movie doesn't
+// exist or is in the trash.
+func (m MovieModel) SetPoster(id int64, key string) (string, error) {
+	query := `WITH previous AS (
+							SELECT id, poster_key FROM movies WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+						)
+						UPDATE movies
+						SET poster_key = $2
+						FROM previous
+						WHERE movies.id = previous.id
+						RETURNING COALESCE(previous.poster_key, '')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var previous string
+
+	err := m.DB.QueryRowContext(ctx, query, id, key).Scan(&previous)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return previous, nil
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+type Rating struct {
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	Rating    int       `json:"rating"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Review struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RatingModel struct {
+	DB *sql.DB
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+func ValidateRating(v *validator.Validator, rating *Rating) {
+	v.Check(rating.Rating >= 1, "rating", "must be at least 1")
+	v.Check(rating.Rating <= 10, "rating", "must not be more than 10")
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Body != "", "body", "must be provided")
+	v.Check(len(review.Body) <= 10_000, "body", "must not be more than 10000 bytes long")
+}
+
+// Upsert stores the rating given by a user to a movie, replacing their previous rating if
+// there is one, and refreshes the aggregated rating of the movie. It returns the updated
+// aggregate, or ErrRecordNotFound if the movie doesn't exist.
+func (m RatingModel) Upsert(rating *Rating) (MovieRating, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return MovieRating{}, err
+	}
+	defer tx.Rollback()
+
+	// Lock the movie row first, so that concurrent ratings of the same movie are applied one
+	// after the other and each one computes the aggregate from up-to-date ratings.
+	query := `SELECT id FROM movies WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, rating.MovieID).Scan(&rating.MovieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return MovieRating{}, ErrRecordNotFound
+		default:
+			return MovieRating{}, err
+		}
+	}
+
+	query = `INSERT INTO ratings (movie_id, user_id, rating)
+						VALUES ($1, $2, $3)
+						ON CONFLICT (movie_id, user_id) DO UPDATE SET rating = EXCLUDED.rating, updated_at = NOW()
+						RETURNING created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query, rating.MovieID, rating.UserID, rating.Rating).Scan(&rating.CreatedAt, &rating.UpdatedAt)
+	if err != nil {
+		return MovieRating{}, err
+	}
+
+	query = `UPDATE movies
+						SET rating = COALESCE((SELECT ROUND(AVG(rating), 2) FROM ratings WHERE movie_id = $1), 0),
+						rating_count = (SELECT COUNT(*) FROM ratings WHERE movie_id = $1)
+						WHERE id = $1
+						RETURNING rating, rating_count`
+
+	var aggregate MovieRating
+
+	err = tx.QueryRowContext(ctx, query, rating.MovieID).Scan(&aggregate.Average, &aggregate.Count)
+	if err != nil {
+		return MovieRating{}, err
+	}
+
+	return aggregate, tx.Commit()
+}
+
+func (m ReviewModel) Insert(review *Review) error {
+	query := `WITH inserted AS (
+							INSERT INTO reviews (movie_id, user_id, body)
+							SELECT id, $2, $3 FROM movies WHERE id = $1 AND deleted_at IS NULL
+							RETURNING id, user_id, created_at
+						)
+						SELECT inserted.id, inserted.created_at, users.name
+						FROM inserted
+						INNER JOIN users ON users.id = inserted.user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, review.MovieID, review.UserID, review.Body).Scan(&review.ID, &review.CreatedAt, &review.Author)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ReviewModel) GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), reviews.id, reviews.movie_id, reviews.user_id, users.name, reviews.body, reviews.created_at
+						FROM reviews
+						INNER JOIN users ON users.id = reviews.user_id
+						WHERE reviews.movie_id = $1
+						ORDER BY reviews.%s %s, reviews.id ASC
+						LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := make([]*Review, 0)
+
+	for rows.Next() {
+		var review Review
+
+		err = rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.MovieID,
+			&review.UserID,
+			&review.Author,
+			&review.Body,
+			&review.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return reviews, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestValidateRating(t *testing.T) {
+	tests := []struct {
+		rating int
+		valid  bool
+	}{
+		{rating: 1, valid: true},
+		{rating: 7, valid: true},
+		{rating: 10, valid: true},
+		{rating: 0, valid: false},
+		{rating: -1, valid: false},
+		{rating: 11, valid: false},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		ValidateRating(v, &Rating{MovieID: 1, UserID: 1, Rating: tt.rating})
+
+		if v.Valide() != tt.valid {
+			t.Errorf("rating %d: got errors %v; want valid %t", tt.rating, v.Errors, tt.valid)
+		}
+	}
+}
+
+func TestValidateReview(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{name: "short body", body: "A classic.", valid: true},
+		{name: "longest body", body: strings.Repeat("a", 10_000), valid: true},
+		{name: "empty body", body: "", valid: false},
+		{name: "too long body", body: strings.Repeat("a", 10_001), valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateReview(v, &Review{MovieID: 1, UserID: 1, Body: tt.body})
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
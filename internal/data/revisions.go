@@ -0,0 +1,226 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MovieSnapshot holds the values of the editable fields of a movie at a given version.
+type MovieSnapshot struct {
+	Title   string   `json:"title"`
+	Year    int32    `json:"year"`
+	Runtime Runtime  `json:"runtime"`
+	Genres  []string `json:"genres"`
+}
+
+// Scan implements the sql.Scanner interface. Snapshots are stored as jsonb objects whose
+// runtime is a plain number of minutes.
+func (s *MovieSnapshot) Scan(src any) error {
+	var js []byte
+
+	switch v := src.(type) {
+	case []byte:
+		js = v
+	case string:
+		js = []byte(v)
+	default:
+		return fmt.Errorf("unsupported snapshot type %T", src)
+	}
+
+	var stored struct {
+		Title   string   `json:"title"`
+		Year    int32    `json:"year"`
+		Runtime int32    `json:"runtime"`
+		Genres  []string `json:"genres"`
+	}
+
+	err := json.Unmarshal(js, &stored)
+	if err != nil {
+		return err
+	}
+
+	*s = MovieSnapshot{
+		Title:   stored.Title,
+		Year:    stored.Year,
+		Runtime: Runtime(stored.Runtime),
+		Genres:  stored.Genres,
+	}
+
+	return nil
+}
+
+// Apply copies the snapshot values to the movie.
+func (s *MovieSnapshot) Apply(movie *Movie) {
+	movie.Title = s.Title
+	movie.Year = s.Year
+	movie.Runtime = s.Runtime
+	movie.Genres = slices.Clone(s.Genres)
+}
+
+// movieSnapshotJSON returns the SQL expression building the jsonb snapshot of the movie row
+// available under the given alias.
+func movieSnapshotJSON(alias string) string {
+	return fmt.Sprintf("jsonb_build_object('title', %[1]s.title, 'year', %[1]s.year, 'runtime', %[1]s.runtime, 'genres', %[1]s.genres)", alias)
+}
+
+type MovieRevision struct {
+	ID        int64          `json:"-"`
+	MovieID   int64          `json:"movie_id"`
+	Version   int32          `json:"version"`
+	Action    string         `json:"action"`  // insert, update, delete or restore
+	UserID    *int64         `json:"user_id"` // nil once the user has been deleted
+	CreatedAt time.Time      `json:"created_at"`
+	Before    *MovieSnapshot `json:"before"`
+	After     *MovieSnapshot `json:"after"`
+}
+
+// FieldChange describes the change of a single field between two snapshots.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffSnapshots returns the fields that differ between two snapshots. A nil snapshot is
+// treated as a movie with empty fields.
+func DiffSnapshots(from, to *MovieSnapshot) []FieldChange {
+	if from == nil {
+		from = &MovieSnapshot{}
+	}
+	if to == nil {
+		to = &MovieSnapshot{}
+	}
+
+	changes := make([]FieldChange, 0)
+
+	if from.Title != to.Title {
+		changes = append(changes, FieldChange{Field: "title", From: from.Title, To: to.Title})
+	}
+	if from.Year != to.Year {
+		changes = append(changes, FieldChange{Field: "year", From: from.Year, To: to.Year})
+	}
+	if from.Runtime != to.Runtime {
+		changes = append(changes, FieldChange{Field: "runtime", From: from.Runtime, To: to.Runtime})
+	}
+	if !slices.Equal(from.Genres, to.Genres) {
+		changes = append(changes, FieldChange{Field: "genres", From: from.Genres, To: to.Genres})
+	}
+
+	return changes
+}
+
+type MovieRevisionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForMovie returns the revisions recorded for a movie, including the revisions of
+// movies currently in the trash.
+func (m MovieRevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*MovieRevision, Metadata, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), id, movie_id, version, action, user_id, created_at, before, after
+						FROM movie_revisions
+						WHERE movie_id = $1
+						ORDER BY %s %s, id ASC
+						LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := make([]*MovieRevision, 0)
+
+	for rows.Next() {
+		var revision MovieRevision
+
+		err = rows.Scan(
+			&totalRecords,
+			&revision.ID,
+			&revision.MovieID,
+			&revision.Version,
+			&revision.Action,
+			&revision.UserID,
+			&revision.CreatedAt,
+			nullSnapshot{&revision.Before},
+			nullSnapshot{&revision.After},
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return revisions, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Get returns the revision that produced the given version of a movie.
+func (m MovieRevisionModel) Get(movieID int64, version int32) (*MovieRevision, error) {
+	if movieID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, movie_id, version, action, user_id, created_at, before, after
+						FROM movie_revisions
+						WHERE movie_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var revision MovieRevision
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&revision.ID,
+		&revision.MovieID,
+		&revision.Version,
+		&revision.Action,
+		&revision.UserID,
+		&revision.CreatedAt,
+		nullSnapshot{&revision.Before},
+		nullSnapshot{&revision.After},
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// nullSnapshot scans a nullable jsonb snapshot column, leaving the destination nil for NULL.
+type nullSnapshot struct {
+	dst **MovieSnapshot
+}
+
+func (n nullSnapshot) Scan(src any) error {
+	if src == nil {
+		*n.dst = nil
+		return nil
+	}
+
+	var s MovieSnapshot
+	err := s.Scan(src)
+	if err != nil {
+		return err
+	}
+
+	*n.dst = &s
+	return nil
+}
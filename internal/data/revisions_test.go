@@ -0,0 +1,128 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	casablanca := &MovieSnapshot{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama", "romance"}}
+
+	tests := []struct {
+		name     string
+		from, to *MovieSnapshot
+		want     []FieldChange
+	}{
+		{
+			name: "no change",
+			from: casablanca,
+			to:   &MovieSnapshot{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"drama", "romance"}},
+			want: []FieldChange{},
+		},
+		{
+			name: "every field",
+			from: casablanca,
+			to:   &MovieSnapshot{Title: "Casablanca (1942)", Year: 1943, Runtime: 103, Genres: []string{"drama"}},
+			want: []FieldChange{
+				{Field: "title", From: "Casablanca", To: "Casablanca (1942)"},
+				{Field: "year", From: int32(1942), To: int32(1943)},
+				{Field: "runtime", From: Runtime(102), To: Runtime(103)},
+				{Field: "genres", From: []string{"drama", "romance"}, To: []string{"drama"}},
+			},
+		},
+		{
+			name: "genres reordered",
+			from: casablanca,
+			to:   &MovieSnapshot{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: []string{"romance", "drama"}},
+			want: []FieldChange{{Field: "genres", From: []string{"drama", "romance"}, To: []string{"romance", "drama"}}},
+		},
+		{
+			name: "insert",
+			from: nil,
+			to:   casablanca,
+			want: []FieldChange{
+				{Field: "title", From: "", To: "Casablanca"},
+				{Field: "year", From: int32(0), To: int32(1942)},
+				{Field: "runtime", From: Runtime(0), To: Runtime(102)},
+				{Field: "genres", From: []string(nil), To: []string{"drama", "romance"}},
+			},
+		},
+		{
+			name: "both nil",
+			want: []FieldChange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffSnapshots(tt.from, tt.to)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v; want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMovieSnapshotScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    MovieSnapshot
+		wantErr bool
+	}{
+		{
+			name: "bytes",
+			src:  []byte(`{"title":"Up","year":2009,"runtime":96,"genres":["animation"]}`),
+			want: MovieSnapshot{Title: "Up", Year: 2009, Runtime: 96, Genres: []string{"animation"}},
+		},
+		{
+			name: "string",
+			src:  `{"title":"Up","year":2009,"runtime":96,"genres":[]}`,
+			want: MovieSnapshot{Title: "Up", Year: 2009, Runtime: 96, Genres: []string{}},
+		},
+		{name: "formatted runtime", src: `{"runtime":"96 mins"}`, wantErr: true},
+		{name: "not JSON", src: []byte("up"), wantErr: true},
+		{name: "unsupported type", src: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got MovieSnapshot
+			err := got.Scan(tt.src)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %+v; want an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMovieSnapshotApply(t *testing.T) {
+	snapshot := &MovieSnapshot{Title: "Up", Year: 2009, Runtime: 96, Genres: []string{"animation"}}
+	movie := &Movie{ID: 7, Title: "Upp", Year: 2008, Runtime: 90, Genres: []string{"comedy"}, Version: 3}
+
+	snapshot.Apply(movie)
+
+	want := &Movie{ID: 7, Title: "Up", Year: 2009, Runtime: 96, Genres: []string{"animation"}, Version: 3}
+	if !reflect.DeepEqual(movie, want) {
+		t.Fatalf("got %+v; want %+v", movie, want)
+	}
+
+	// The genres are copied, so that editing the movie doesn't change the snapshot.
+	movie.Genres[0] = "comedy"
+	if snapshot.Genres[0] != "animation" {
+		t.Errorf("the snapshot genres were changed along with the movie")
+	}
+}
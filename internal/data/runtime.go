@@ -3,12 +3,30 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 var ErrInvalidRuntimeFormat = errors.New("invalide runtime format")
 
+// Define the formats a runtime can be represented with in responses.
+const (
+	RuntimeFormatMins    = "mins"    // "105 mins", the default
+	RuntimeFormatHM      = "hm"      // "1h 45m"
+	RuntimeFormatISO8601 = "iso8601" // "PT1H45M"
+	RuntimeFormatInteger = "integer" // 105
+)
+
+var RuntimeFormats = []string{RuntimeFormatMins, RuntimeFormatHM, RuntimeFormatISO8601, RuntimeFormatInteger}
+
+var (
+	runtimeMinsRX    = regexp.MustCompile(`^(\d+)\s*mins?$`)
+	runtimeHMRX      = regexp.MustCompile(`^(?:(\d+)\s*h)?\s*(?:(\d+)\s*m)?$`)
+	runtimeISO8601RX = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+)
+
 type Runtime int32
 
 // Implement json.Marshaller interface by providing its MarshalJSON method on the Runtime type
@@ -21,25 +39,119 @@ func (r Runtime) MarshalJSON() ([]byte, error) {
 	return []byte(quotedJSONValue), nil
 }
 
+// UnmarshalJSON accepts a number of minutes, either as a JSON number or as a string in any of
+// the forms accepted by ParseRuntime.
 func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	// the expected incoming JSON value is "<runtime> mins". so the first thing to do is remove the quotes
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
-	if err != nil {
-		return ErrInvalidRuntimeFormat
+	// By convention, null leaves the value unchanged.
+	if string(jsonValue) == "null" {
+		return nil
 	}
 
-	parts := strings.Split(unquotedJSONValue, " ")
+	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+	if err != nil {
+		// Not a string, so it must be a plain number of minutes.
+		i, err := strconv.ParseInt(string(jsonValue), 10, 32)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be a whole number of minutes", ErrInvalidRuntimeFormat, jsonValue)
+		}
 
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
+		*r = Runtime(i)
+		return nil
 	}
 
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+	runtime, err := ParseRuntime(unquotedJSONValue)
 	if err != nil {
-		return ErrInvalidRuntimeFormat
+		return err
 	}
 
-	*r = Runtime(i)
+	*r = runtime
 
 	return nil
 }
+
+// ParseRuntime parses a runtime given as a number of minutes ("105"), with the unit ("105
+// mins"), in hours and minutes ("1h 45m") or as an ISO 8601 duration ("PT1H45M"). Errors wrap
+// ErrInvalidRuntimeFormat.
+func ParseRuntime(s string) (Runtime, error) {
+	s = strings.TrimSpace(s)
+
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %q %s", ErrInvalidRuntimeFormat, s, reason)
+	}
+
+	var hours, minutes string
+
+	if m := runtimeISO8601RX.FindStringSubmatch(strings.ToUpper(s)); m != nil {
+		if m[1] == "" && m[2] == "" && m[3] == "" {
+			return 0, invalid("must contain hours or minutes")
+		}
+		if m[3] != "" && strings.Trim(m[3], "0") != "" {
+			return 0, invalid("must be a whole number of minutes")
+		}
+		hours, minutes = m[1], m[2]
+	} else if m := runtimeMinsRX.FindStringSubmatch(s); m != nil {
+		minutes = m[1]
+	} else if m := runtimeHMRX.FindStringSubmatch(s); m != nil && s != "" {
+		hours, minutes = m[1], m[2]
+	} else if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		minutes = s
+	} else {
+		return 0, invalid(`must be a number of minutes, "<n> mins", "1h 45m" or an ISO 8601 duration like "PT1H45M"`)
+	}
+
+	total := int64(0)
+
+	if hours != "" {
+		h, err := strconv.ParseInt(hours, 10, 32)
+		if err != nil {
+			return 0, invalid("is too long")
+		}
+		total += h * 60
+	}
+
+	if minutes != "" {
+		m, err := strconv.ParseInt(minutes, 10, 32)
+		if err != nil {
+			return 0, invalid("is too long")
+		}
+		total += m
+	}
+
+	if total > math.MaxInt32 {
+		return 0, invalid("is too long")
+	}
+	if total < math.MinInt32 {
+		return 0, invalid("is too short")
+	}
+
+	return Runtime(total), nil
+}
+
+// Format returns the representation of the runtime in the given format, which is a string for
+// every format but RuntimeFormatInteger.
+func (r Runtime) Format(format string) any {
+	switch format {
+	case RuntimeFormatHM:
+		switch {
+		case r < 60:
+			return fmt.Sprintf("%dm", r)
+		case r%60 == 0:
+			return fmt.Sprintf("%dh", r/60)
+		default:
+			return fmt.Sprintf("%dh %dm", r/60, r%60)
+		}
+	case RuntimeFormatISO8601:
+		switch {
+		case r < 60:
+			return fmt.Sprintf("PT%dM", r)
+		case r%60 == 0:
+			return fmt.Sprintf("PT%dH", r/60)
+		default:
+			return fmt.Sprintf("PT%dH%dM", r/60, r%60)
+		}
+	case RuntimeFormatInteger:
+		return int32(r)
+	default:
+		return fmt.Sprintf("%d mins", r)
+	}
+}
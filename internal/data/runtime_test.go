@@ -0,0 +1,141 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRuntime(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Runtime
+		wantErr bool
+	}{
+		{input: "105", want: 105},
+		{input: "105 mins", want: 105},
+		{input: "1 min", want: 1},
+		{input: "105mins", want: 105},
+		{input: "  105 mins  ", want: 105},
+		{input: "1h 45m", want: 105},
+		{input: "1h45m", want: 105},
+		{input: "2h", want: 120},
+		{input: "45m", want: 45},
+		{input: "PT1H45M", want: 105},
+		{input: "pt1h45m", want: 105},
+		{input: "PT2H", want: 120},
+		{input: "PT45M", want: 45},
+		{input: "PT1H45M0S", want: 105},
+		{input: "0", want: 0},
+		{input: "2147483647", want: 2147483647},
+		{input: "", wantErr: true},
+		{input: "PT", wantErr: true},
+		{input: "PT1H45M30S", wantErr: true},
+		{input: "P1D", wantErr: true},
+		{input: "1.5h", wantErr: true},
+		{input: "105 minutes", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "2147483648", wantErr: true},
+		{input: "-2147483649", wantErr: true},
+		{input: "99999999999999999999", wantErr: true},
+		{input: "35791395h", wantErr: true},
+		{input: "PT2147483647H2147483647M", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRuntime(tt.input)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRuntimeFormat) {
+					t.Errorf("got %d, %v; want ErrInvalidRuntimeFormat", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeFormat(t *testing.T) {
+	tests := []struct {
+		runtime Runtime
+		format  string
+		want    any
+	}{
+		{105, RuntimeFormatMins, "105 mins"},
+		{105, RuntimeFormatHM, "1h 45m"},
+		{120, RuntimeFormatHM, "2h"},
+		{45, RuntimeFormatHM, "45m"},
+		{105, RuntimeFormatISO8601, "PT1H45M"},
+		{120, RuntimeFormatISO8601, "PT2H"},
+		{45, RuntimeFormatISO8601, "PT45M"},
+		{0, RuntimeFormatISO8601, "PT0M"},
+		{105, RuntimeFormatInteger, int32(105)},
+		{105, "", "105 mins"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got := tt.runtime.Format(tt.format)
+			if got != tt.want {
+				t.Errorf("Format(%q) of %d = %#v; want %#v", tt.format, tt.runtime, got, tt.want)
+			}
+
+			// Every format but the integer one must parse back into the same runtime.
+			if s, ok := got.(string); ok {
+				parsed, err := ParseRuntime(s)
+				if err != nil || parsed != tt.runtime {
+					t.Errorf("ParseRuntime(%q) = %d, %v; want %d", s, parsed, err, tt.runtime)
+				}
+			}
+		})
+	}
+}
+
+func TestRuntimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Runtime
+		wantErr bool
+	}{
+		{input: `105`, want: 105},
+		{input: `"105 mins"`, want: 105},
+		{input: `"1h 45m"`, want: 105},
+		{input: `"PT1H45M"`, want: 105},
+		{input: `null`, want: 7},
+		{input: `1.5`, wantErr: true},
+		{input: `2147483648`, wantErr: true},
+		{input: `"soon"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			// null leaves the value unchanged.
+			got := Runtime(7)
+
+			err := got.UnmarshalJSON([]byte(tt.input))
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRuntimeFormat) {
+					t.Errorf("got %d, %v; want ErrInvalidRuntimeFormat", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SimilarityWeights sets how much each criterion counts in the similarity of two movies. The
+// weights are relative to each other, only their proportions matter.
+type SimilarityWeights struct {
+	Genres  float64
+	Year    float64
+	Runtime float64
+}
+
+// normalized scales the weights so that they add up to 1, falling back to equal weights when
+// none of them is positive.
+func (w SimilarityWeights) normalized() SimilarityWeights {
+	total := w.Genres + w.Year + w.Runtime
+	if total <= 0 {
+		return SimilarityWeights{Genres: 1.0 / 3, Year: 1.0 / 3, Runtime: 1.0 / 3}
+	}
+
+	return SimilarityWeights{Genres: w.Genres / total, Year: w.Year / total, Runtime: w.Runtime / total}
+}
+
+// movieSimilarity returns the join giving each movie its similarity, between 0 and 1, to a
+// reference movie. It is bound to six parameters starting at $first: the genres, year and
+// runtime of the reference movie, then the normalized weights of each criterion. The genres
+// are compared with the Jaccard index, while the year and runtime scores halve every 10 years
+// and 30 minutes apart respectively.
+func movieSimilarity(first int) string {
+	return fmt.Sprintf(`CROSS JOIN LATERAL (
+							SELECT
+							$%[4]d::float8 * cardinality(ARRAY(SELECT unnest(genres) INTERSECT SELECT unnest($%[1]d::text[])))
+								/ GREATEST(cardinality(ARRAY(SELECT unnest(genres) UNION SELECT unnest($%[1]d::text[]))), 1)
+							+ $%[5]d::float8 / (1 + abs(year - $%[2]d::integer) / 10.0)
+							+ $%[6]d::float8 / (1 + abs(runtime - $%[3]d::integer) / 30.0) AS similarity
+						) s`, first, first+1, first+2, first+3, first+4, first+5)
+}
+
+// Similar returns the movies sharing at least one genre with the given movie, matching the
+// search, along with their similarity to it. The movie itself is left out.
+func (m MovieModel) Similar(movie *Movie, weights SimilarityWeights, search MovieSearch, filters Filters) ([]*Movie, Metadata, error) {
+	weights = weights.normalized()
+
+	args := search.args()
+	n := len(args)
+
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), id, created_at, title, year, runtime, genres, version, rating, rating_count, poster_key, similarity
+						FROM movies
+						%s
+						WHERE deleted_at IS NULL
+						AND id <> $%d
+						AND genres && $%d
+						AND %s
+						ORDER BY %s %s, id ASC
+						LIMIT $%d OFFSET $%d`, movieSimilarity(n+1), n+7, n+1, movieSearchCondition, filters.sortColumn(), filters.sortDirection(), n+8, n+9)
+
+	args = append(args,
+		pq.Array(movie.Genres), movie.Year, movie.Runtime,
+		weights.Genres, weights.Year, weights.Runtime,
+		movie.ID, filters.limit(), filters.offset(),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := make([]*Movie, 0)
+
+	for rows.Next() {
+		var movie Movie
+
+		err = rows.Scan(
+			&totalRecords,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.Rating.Average,
+			&movie.Rating.Count,
+			&movie.PosterURL,
+			&movie.Similarity,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return movies, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
@@ -0,0 +1,31 @@
+package data
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarityWeightsNormalized(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights SimilarityWeights
+		want    SimilarityWeights
+	}{
+		{name: "already normalized", weights: SimilarityWeights{0.6, 0.25, 0.15}, want: SimilarityWeights{0.6, 0.25, 0.15}},
+		{name: "proportions", weights: SimilarityWeights{2, 1, 1}, want: SimilarityWeights{0.5, 0.25, 0.25}},
+		{name: "single criterion", weights: SimilarityWeights{0, 0, 4}, want: SimilarityWeights{0, 0, 1}},
+		{name: "no positive weight", weights: SimilarityWeights{}, want: SimilarityWeights{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.weights.normalized()
+
+			for _, pair := range [][2]float64{{got.Genres, tt.want.Genres}, {got.Year, tt.want.Year}, {got.Runtime, tt.want.Runtime}} {
+				if math.Abs(pair[0]-pair[1]) > 1e-9 {
+					t.Fatalf("got %+v; want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
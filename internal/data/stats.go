@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieStats holds aggregates over the movies matching a search.
+type MovieStats struct {
+	Totals        MovieTotals             `json:"totals"`
+	Runtime       RuntimePercentiles      `json:"runtime_percentiles"`
+	Counts        map[string][]FacetCount `json:"counts"`
+	RuntimeByYear []YearRuntime           `json:"runtime_by_year"`
+	GeneratedAt   time.Time               `json:"generated_at"`
+}
+
+type MovieTotals struct {
+	Movies         int     `json:"movies"`
+	AverageRuntime float64 `json:"average_runtime"`
+	MinYear        int32   `json:"min_year,omitempty"`
+	MaxYear        int32   `json:"max_year,omitempty"`
+}
+
+// RuntimePercentiles holds the percentiles of the runtimes, in minutes. They are all zero
+// when no movie matches.
+type RuntimePercentiles struct {
+	P25 float64 `json:"p25"`
+	P50 float64 `json:"p50"`
+	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90"`
+}
+
+// YearRuntime is the average runtime of the movies released in a year.
+type YearRuntime struct {
+	Year           int32   `json:"year"`
+	Movies         int     `json:"movies"`
+	AverageRuntime float64 `json:"average_runtime"`
+}
+
+// Stats computes the aggregates of the movies matching the search. The counts by genre, year
+// and decade are the facets of MovieModel.Facets.
+func (m MovieModel) Stats(search MovieSearch) (*MovieStats, error) {
+	stats := &MovieStats{GeneratedAt: time.Now()}
+
+	query := fmt.Sprintf(`SELECT COUNT(*), COALESCE(AVG(runtime), 0), COALESCE(MIN(year), 0), COALESCE(MAX(year), 0),
+						percentile_cont(ARRAY[0.25, 0.5, 0.75, 0.9]) WITHIN GROUP (ORDER BY runtime)
+						FROM movies
+						WHERE deleted_at IS NULL
+						AND %s`, movieSearchCondition)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var percentiles pq.Float64Array
+
+	err := m.DB.QueryRowContext(ctx, query, search.args()...).Scan(
+		&stats.Totals.Movies,
+		&stats.Totals.AverageRuntime,
+		&stats.Totals.MinYear,
+		&stats.Totals.MaxYear,
+		&percentiles,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The percentiles are NULL when no movie matches.
+	if len(percentiles) == 4 {
+		stats.Runtime = RuntimePercentiles{P25: percentiles[0], P50: percentiles[1], P75: percentiles[2], P90: percentiles[3]}
+	}
+
+	query = fmt.Sprintf(`SELECT year, COUNT(*), AVG(runtime)
+						FROM movies
+						WHERE deleted_at IS NULL
+						AND %s
+						GROUP BY year
+						ORDER BY year ASC`, movieSearchCondition)
+
+	rows, err := m.DB.QueryContext(ctx, query, search.args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stats.RuntimeByYear = make([]YearRuntime, 0)
+
+	for rows.Next() {
+		var year YearRuntime
+
+		err = rows.Scan(&year.Year, &year.Movies, &year.AverageRuntime)
+		if err != nil {
+			return nil, err
+		}
+		stats.RuntimeByYear = append(stats.RuntimeByYear, year)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.Counts, err = m.Facets(search, []string{"genres", "year", "decade"})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/text/language"
+	"greenlight.hichammou/internal/validator"
+)
+
+// MovieTitle is the title of a movie in a given language, identified by a BCP 47 tag such as
+// "fr" or "pt-BR".
+type MovieTitle struct {
+	MovieID  int64  `json:"-"`
+	Language string `json:"language"`
+	Title    string `json:"title"`
+}
+
+type MovieTitleModel struct {
+	DB             *sql.DB
+	onSearchChange searchListener
+}
+
+// ValidateMovieTitle checks a localized title, normalizing its language tag to its canonical
+// form on the way.
+func ValidateMovieTitle(v *validator.Validator, title *MovieTitle) {
+	tag, err := language.Parse(title.Language)
+	if err != nil || tag == language.Und {
+		v.AddError("language", "must be a valid BCP 47 language tag")
+	} else {
+		title.Language = tag.String()
+	}
+
+	v.Check(title.Title != "", "title", "must be provided")
+	v.Check(len(title.Title) <= 500, "title", "must not be more than 500 bytes long")
+}
+
+func (m MovieTitleModel) GetAllForMovie(movieID int64) ([]*MovieTitle, error) {
+	titles, err := m.GetAllForMovies([]int64{movieID})
+	if err != nil {
+		return nil, err
+	}
+
+	if titles[movieID] == nil {
+		return []*MovieTitle{}, nil
+	}
+
+	return titles[movieID], nil
+}
+
+// GetAllForMovies returns the localized titles of several movies at once, keyed by movie id.
+func (m MovieTitleModel) GetAllForMovies(movieIDs []int64) (map[int64][]*MovieTitle, error) {
+	query := `SELECT movie_id, language, title
+						FROM movie_titles
+						WHERE movie_id = ANY($1)
+						ORDER BY movie_id ASC, language ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	titles := make(map[int64][]*MovieTitle)
+
+	for rows.Next() {
+		var title MovieTitle
+
+		err = rows.Scan(&title.MovieID, &title.Language, &title.Title)
+		if err != nil {
+			return nil, err
+		}
+		titles[title.MovieID] = append(titles[title.MovieID], &title)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return titles, nil
+}
+
+// Upsert sets the title of a movie in a language, replacing the previous one if any. It
+// returns ErrRecordNotFound if the movie doesn't exist or is in the trash.
+func (m MovieTitleModel) Upsert(title *MovieTitle) error {
+	query := `INSERT INTO movie_titles (movie_id, language, title)
+						SELECT id, $2, $3 FROM movies WHERE id = $1 AND deleted_at IS NULL
+						ON CONFLICT (movie_id, language) DO UPDATE SET title = EXCLUDED.title`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, title.MovieID, title.Language, title.Title)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+func (m MovieTitleModel) Delete(movieID int64, lang string) error {
+	query := `DELETE FROM movie_titles WHERE movie_id = $1 AND language = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, lang)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	m.onSearchChange.notify()
+
+	return nil
+}
+
+// Localize replaces the title of the movie by the one of titles which best matches the
+// languages accepted by the client, in order of preference, and keeps the original title in
+// OriginalTitle. The original title is kept when none of them is acceptable. It returns
+// whether the title was replaced.
+func (movie *Movie) Localize(accepted []language.Tag, titles []*MovieTitle) bool {
+	movie.OriginalTitle = movie.Title
+
+	if len(accepted) == 0 || len(titles) == 0 {
+		return false
+	}
+
+	// The first supported tag is the fallback of the matcher, it stands for the original title.
+	supported := []language.Tag{language.Und}
+	for _, title := range titles {
+		supported = append(supported, language.Make(title.Language))
+	}
+
+	_, index, confidence := language.NewMatcher(supported).Match(accepted...)
+	if index == 0 || confidence == language.No {
+		return false
+	}
+
+	movie.Title = titles[index-1].Title
+	return true
+}
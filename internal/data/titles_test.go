@@ -0,0 +1,84 @@
+package data
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestMovieLocalize(t *testing.T) {
+	titles := []*MovieTitle{
+		{Language: "de", Title: "Der Pate"},
+		{Language: "fr", Title: "Le Parrain"},
+		{Language: "pt-BR", Title: "O Poderoso Chefão"},
+	}
+
+	tests := []struct {
+		name   string
+		accept string
+		titles []*MovieTitle
+		want   string
+	}{
+		{name: "no preference", accept: "", titles: titles, want: "The Godfather"},
+		{name: "no titles", accept: "fr", titles: nil, want: "The Godfather"},
+		{name: "exact language", accept: "fr", titles: titles, want: "Le Parrain"},
+		{name: "regional variant", accept: "fr-CA", titles: titles, want: "Le Parrain"},
+		{name: "regional title", accept: "pt-BR", titles: titles, want: "O Poderoso Chefão"},
+		{name: "order of preference", accept: "de;q=0.5, fr;q=0.9", titles: titles, want: "Le Parrain"},
+		{name: "first acceptable language", accept: "ja, de", titles: titles, want: "Der Pate"},
+		{name: "no acceptable language", accept: "ja, ko", titles: titles, want: "The Godfather"},
+		{name: "any language", accept: "*", titles: titles, want: "The Godfather"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted, _, err := language.ParseAcceptLanguage(tt.accept)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			movie := &Movie{Title: "The Godfather"}
+			localized := movie.Localize(accepted, tt.titles)
+
+			if movie.Title != tt.want {
+				t.Errorf("got title %q; want %q", movie.Title, tt.want)
+			}
+			if movie.OriginalTitle != "The Godfather" {
+				t.Errorf("got original title %q; want %q", movie.OriginalTitle, "The Godfather")
+			}
+			if localized != (tt.want != "The Godfather") {
+				t.Errorf("got localized %t for title %q", localized, movie.Title)
+			}
+		})
+	}
+}
+
+func TestValidateMovieTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    MovieTitle
+		valid    bool
+		language string
+	}{
+		{name: "valid", title: MovieTitle{Language: "fr", Title: "Le Parrain"}, valid: true, language: "fr"},
+		{name: "canonical tag", title: MovieTitle{Language: "pt-br", Title: "O Poderoso Chefão"}, valid: true, language: "pt-BR"},
+		{name: "invalid tag", title: MovieTitle{Language: "not a tag", Title: "Le Parrain"}, valid: false},
+		{name: "undetermined language", title: MovieTitle{Language: "und", Title: "Le Parrain"}, valid: false},
+		{name: "missing title", title: MovieTitle{Language: "fr"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateMovieTitle(v, &tt.title)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+			if tt.valid && tt.title.Language != tt.language {
+				t.Errorf("got language %q; want %q", tt.title.Language, tt.language)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"greenlight.hichammou/internal/validator"
+)
+
+var (
+	ErrDuplicateWatchlistEntry = errors.New("duplicate watchlist entry")
+)
+
+// WatchlistEntry is a movie saved by a user. Entries of movies in the trash are hidden, and
+// they are removed along with the movie when the trash is purged.
+type WatchlistEntry struct {
+	UserID  int64     `json:"-"`
+	MovieID int64     `json:"-"`
+	Movie   *Movie    `json:"movie"`
+	AddedAt time.Time `json:"added_at"`
+	Watched bool      `json:"watched"`
+	Notes   string    `json:"notes"`
+}
+
+type WatchlistModel struct {
+	DB *sql.DB
+}
+
+func ValidateWatchlistEntry(v *validator.Validator, entry *WatchlistEntry) {
+	v.Check(entry.MovieID > 0, "movie_id", "must be provided")
+	v.Check(len(entry.Notes) <= 1000, "notes", "must not be more than 1000 bytes long")
+}
+
+func (m WatchlistModel) Insert(entry *WatchlistEntry) error {
+	query := `INSERT INTO watchlist_entries (user_id, movie_id, watched, notes)
+						SELECT $1, id, $3, $4 FROM movies WHERE id = $2 AND deleted_at IS NULL
+						RETURNING added_at`
+
+	args := []any{entry.UserID, entry.MovieID, entry.Watched, entry.Notes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&entry.AddedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "watchlist_entries_pkey"`:
+			return ErrDuplicateWatchlistEntry
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m WatchlistModel) Get(userID, movieID int64) (*WatchlistEntry, error) {
+	query := `SELECT w.added_at, w.watched, w.notes,
+						m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.version, m.rating, m.rating_count, m.poster_key
+						FROM watchlist_entries w
+						INNER JOIN movies m ON m.id = w.movie_id
+						WHERE w.user_id = $1 AND w.movie_id = $2 AND m.deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	entry := WatchlistEntry{UserID: userID, MovieID: movieID, Movie: &Movie{}}
+
+	err := m.DB.QueryRowContext(ctx, query, userID, movieID).Scan(
+		&entry.AddedAt,
+		&entry.Watched,
+		&entry.Notes,
+		&entry.Movie.ID,
+		&entry.Movie.CreatedAt,
+		&entry.Movie.Title,
+		&entry.Movie.Year,
+		&entry.Movie.Runtime,
+		pq.Array(&entry.Movie.Genres),
+		&entry.Movie.Version,
+		&entry.Movie.Rating.Average,
+		&entry.Movie.Rating.Count,
+		&entry.Movie.PosterURL,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// GetAllForUser returns the watchlist of a user, filtered with the same search parameters as
+// MovieModel.List. A non-nil watched only returns the entries with that flag.
+func (m WatchlistModel) GetAllForUser(userID int64, search MovieSearch, watched *bool, filters Filters) ([]*WatchlistEntry, Metadata, error) {
+	args := search.args()
+	n := len(args)
+
+	query := fmt.Sprintf(`SELECT COUNT(*) OVER(), w.added_at, w.watched, w.notes,
+						m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.version, m.rating, m.rating_count, m.poster_key
+						FROM watchlist_entries w
+						INNER JOIN movies m ON m.id = w.movie_id
+						WHERE w.user_id = $%[4]d
+						AND m.deleted_at IS NULL
+						AND %[1]s
+						AND ($%[5]d::boolean IS NULL OR w.watched = $%[5]d)
+						ORDER BY %[2]s %[3]s, m.id ASC
+						LIMIT $%[6]d OFFSET $%[7]d`, movieSearchCondition, filters.sortColumn(), filters.sortDirection(), n+1, n+2, n+3, n+4)
+
+	args = append(args, userID, watched, filters.limit(), filters.offset())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := make([]*WatchlistEntry, 0)
+
+	for rows.Next() {
+		entry := WatchlistEntry{UserID: userID, Movie: &Movie{}}
+
+		err = rows.Scan(
+			&totalRecords,
+			&entry.AddedAt,
+			&entry.Watched,
+			&entry.Notes,
+			&entry.Movie.ID,
+			&entry.Movie.CreatedAt,
+			&entry.Movie.Title,
+			&entry.Movie.Year,
+			&entry.Movie.Runtime,
+			pq.Array(&entry.Movie.Genres),
+			&entry.Movie.Version,
+			&entry.Movie.Rating.Average,
+			&entry.Movie.Rating.Count,
+			&entry.Movie.PosterURL,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		entry.MovieID = entry.Movie.ID
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return entries, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (m WatchlistModel) Update(entry *WatchlistEntry) error {
+	query := `UPDATE watchlist_entries
+						SET watched = $3, notes = $4
+						WHERE user_id = $1 AND movie_id = $2`
+
+	args := []any{entry.UserID, entry.MovieID, entry.Watched, entry.Notes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m WatchlistModel) Delete(userID, movieID int64) error {
+	query := `DELETE FROM watchlist_entries WHERE user_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
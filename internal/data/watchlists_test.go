@@ -0,0 +1,33 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"greenlight.hichammou/internal/validator"
+)
+
+func TestValidateWatchlistEntry(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry WatchlistEntry
+		valid bool
+	}{
+		{name: "movie only", entry: WatchlistEntry{MovieID: 1}, valid: true},
+		{name: "longest notes", entry: WatchlistEntry{MovieID: 1, Notes: strings.Repeat("a", 1000)}, valid: true},
+		{name: "no movie", entry: WatchlistEntry{Notes: "later"}, valid: false},
+		{name: "negative movie id", entry: WatchlistEntry{MovieID: -1}, valid: false},
+		{name: "too long notes", entry: WatchlistEntry{MovieID: 1, Notes: strings.Repeat("a", 1001)}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateWatchlistEntry(v, &tt.entry)
+
+			if v.Valide() != tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSystemPath(t *testing.T) {
+	root := t.TempDir()
+
+	s, err := NewFileSystem(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "42/poster.jpg", want: filepath.Join(root, "42", "poster.jpg")},
+		{key: "poster.jpg", want: filepath.Join(root, "poster.jpg")},
+		{key: "42/../43/poster.jpg", want: filepath.Join(root, "43", "poster.jpg")},
+		{key: "42//poster.jpg", want: filepath.Join(root, "42", "poster.jpg")},
+		{key: "", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../poster.jpg", wantErr: true},
+		{key: "../../etc/passwd", wantErr: true},
+		{key: "42/../../poster.jpg", wantErr: true},
+		{key: "/etc/passwd", wantErr: true},
+		{key: "/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := s.path(tt.key)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidKey) {
+					t.Errorf("got %q, %v; want ErrInvalidKey", got, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSystemTraversal(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := NewFileSystem(filepath.Join(dir, "root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file next to the root directory, which no key must reach.
+	outside := filepath.Join(dir, "secret")
+
+	err = os.WriteFile(outside, []byte("secret"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put("../secret", strings.NewReader("overwritten")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put: got %v; want ErrInvalidKey", err)
+	}
+
+	if _, _, err := s.Get("../secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get: got %v; want ErrInvalidKey", err)
+	}
+
+	if err := s.Delete("../secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Delete: got %v; want ErrInvalidKey", err)
+	}
+
+	content, err := os.ReadFile(outside)
+	if err != nil || string(content) != "secret" {
+		t.Errorf("the file outside of the root was changed: %q, %v", content, err)
+	}
+}
+
+func TestFileSystemPutGetDelete(t *testing.T) {
+	s, err := NewFileSystem(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Put("42/poster.jpg", strings.NewReader("image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, _, err := s.Get("42/poster.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(content) != "image" {
+		t.Errorf("got %q, %v; want %q", content, err, "image")
+	}
+
+	// Directories aren't objects.
+	if _, _, err := s.Get("42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a directory: got %v; want ErrNotFound", err)
+	}
+
+	err = s.Delete("42/poster.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := s.Get("42/poster.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: got %v; want ErrNotFound", err)
+	}
+
+	// Deleting a missing object isn't an error.
+	if err := s.Delete("42/poster.jpg"); err != nil {
+		t.Errorf("second Delete: got %v; want nil", err)
+	}
+}
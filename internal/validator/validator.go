@@ -6,6 +6,13 @@ var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// ExternalIDRX maps the providers of external identifiers to the format of their identifiers.
+var ExternalIDRX = map[string]*regexp.Regexp{
+	"imdb":     regexp.MustCompile(`^tt\d{7,}$`),
+	"tmdb":     regexp.MustCompile(`^[1-9]\d*$`),
+	"wikidata": regexp.MustCompile(`^Q[1-9]\d*$`),
+}
+
 type Validator struct {
 	Errors map[string]string
 }
@@ -49,9 +56,16 @@ func Matches(s string, rx regexp.Regexp) bool {
 	return rx.MatchString(s)
 }
 
-// Unique returns true if all string values in a slice are unique.
-func Unique(values []string) bool {
-	uniqueValues := make(map[string]bool)
+// ExternalID returns true if value is a well-formed identifier of the given provider, and
+// false if it isn't or the provider is unknown.
+func ExternalID(provider, value string) bool {
+	rx, ok := ExternalIDRX[provider]
+	return ok && rx.MatchString(value)
+}
+
+// Unique returns true if all values in a slice are unique.
+func Unique[T comparable](values []T) bool {
+	uniqueValues := make(map[T]bool)
 
 	for _, value := range values {
 		uniqueValues[value] = true
@@ -0,0 +1,34 @@
+package validator
+
+import "testing"
+
+func TestExternalID(t *testing.T) {
+	tests := []struct {
+		provider string
+		value    string
+		want     bool
+	}{
+		{provider: "imdb", value: "tt0068646", want: true},
+		{provider: "imdb", value: "tt10872600", want: true},
+		{provider: "imdb", value: "tt068646", want: false},
+		{provider: "imdb", value: "0068646", want: false},
+		{provider: "imdb", value: "TT0068646", want: false},
+		{provider: "imdb", value: " tt0068646", want: false},
+		{provider: "tmdb", value: "238", want: true},
+		{provider: "tmdb", value: "0238", want: false},
+		{provider: "tmdb", value: "0", want: false},
+		{provider: "tmdb", value: "-238", want: false},
+		{provider: "wikidata", value: "Q47703", want: true},
+		{provider: "wikidata", value: "q47703", want: false},
+		{provider: "wikidata", value: "Q0", want: false},
+		{provider: "letterboxd", value: "the-godfather", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+" "+tt.value, func(t *testing.T) {
+			if got := ExternalID(tt.provider, tt.value); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+  id bigserial PRIMARY KEY,
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  version integer NOT NULL,
+  action text NOT NULL,
+  user_id bigint REFERENCES users ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  before jsonb,
+  after jsonb,
+  UNIQUE (movie_id, version)
+);
+
+-- Record the current state of the existing movies as their first known revision.
+INSERT INTO movie_revisions (movie_id, version, action, created_at, after)
+SELECT id, version, 'insert', created_at, jsonb_build_object('title', title, 'year', year, 'runtime', runtime, 'genres', genres)
+FROM movies
+ON CONFLICT DO NOTHING;
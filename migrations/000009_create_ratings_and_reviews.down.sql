@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE code = 'reviews:write';
+
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS ratings;
+
+DROP INDEX IF EXISTS movies_rating_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE movies DROP COLUMN IF EXISTS rating;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS watchlist_entries (
+  user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  watched bool NOT NULL DEFAULT false,
+  notes text NOT NULL DEFAULT '',
+  PRIMARY KEY (user_id, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS watchlist_entries_movie_id_idx ON watchlist_entries (movie_id);
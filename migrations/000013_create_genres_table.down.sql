@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE code = 'genres:write';
+
+-- The genres of the movies stay normalized.
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS genres (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  slug text NOT NULL UNIQUE,
+  name text NOT NULL,
+  aliases text[] NOT NULL DEFAULT '{}',
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS genres_aliases_idx ON genres USING GIN (aliases);
+
+-- Normalize the genres of the existing movies to slugs: lowercase words separated by dashes,
+-- so that "Sci-Fi" and "sci-fi" become the same genre. Genres with no letter or digit at all
+-- become "other", as a movie must keep at least one genre. Every modified movie gets a new
+-- version and revision, like any other update.
+WITH normalized AS (
+  SELECT m.id, ARRAY(
+    SELECT s.slug
+    FROM (
+      SELECT COALESCE(NULLIF(trim(BOTH '-' FROM regexp_replace(lower(g.genre), '[^a-z0-9]+', '-', 'g')), ''), 'other') AS slug,
+      MIN(g.position) AS position
+      FROM unnest(m.genres) WITH ORDINALITY AS g(genre, position)
+      GROUP BY 1
+    ) s
+    ORDER BY s.position
+  ) AS genres
+  FROM movies m
+), updated AS (
+  UPDATE movies m
+  SET genres = n.genres, version = m.version + 1
+  FROM normalized n
+  WHERE m.id = n.id AND m.genres <> n.genres
+  RETURNING m.id, m.version, m.title, m.year, m.runtime, m.genres
+)
+INSERT INTO movie_revisions (movie_id, version, action, before, after)
+SELECT u.id, u.version, 'update',
+jsonb_build_object('title', b.title, 'year', b.year, 'runtime', b.runtime, 'genres', b.genres),
+jsonb_build_object('title', u.title, 'year', u.year, 'runtime', u.runtime, 'genres', u.genres)
+FROM updated u
+INNER JOIN movies b ON b.id = u.id;
+
+-- Seed the taxonomy with the genres in use.
+INSERT INTO genres (slug, name)
+SELECT DISTINCT g.slug, initcap(replace(g.slug, '-', ' '))
+FROM movies, unnest(genres) AS g(slug)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions (code)
+VALUES ('genres:write');
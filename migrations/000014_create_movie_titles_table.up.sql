@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS movie_titles (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  language text NOT NULL,
+  title text NOT NULL,
+  PRIMARY KEY (movie_id, language)
+);
+
+-- The localized titles are searched like the original ones.
+CREATE INDEX IF NOT EXISTS movie_titles_title_idx ON movie_titles USING GIN (to_tsvector('simple', title));
+CREATE INDEX IF NOT EXISTS movie_titles_title_trgm_idx ON movie_titles USING GIN (title gin_trgm_ops);
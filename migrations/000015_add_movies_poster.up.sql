@@ -0,0 +1,2 @@
+-- The storage key of the original poster image, NULL when the movie has no poster.
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS poster_key text;
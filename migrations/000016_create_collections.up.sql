@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS collections (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  name text NOT NULL,
+  description text NOT NULL DEFAULT '',
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS collections_name_idx ON collections USING GIN (to_tsvector('simple', name));
+
+-- A movie belongs to at most one collection, at a given position in it.
+CREATE TABLE IF NOT EXISTS collection_movies (
+  collection_id bigint NOT NULL REFERENCES collections ON DELETE CASCADE,
+  movie_id bigint NOT NULL UNIQUE REFERENCES movies ON DELETE CASCADE,
+  position integer NOT NULL,
+  PRIMARY KEY (collection_id, movie_id),
+  UNIQUE (collection_id, position)
+);
+
+INSERT INTO permissions (code)
+VALUES ('collections:read'), ('collections:write');
+
+-- Users who can read movies can read the collections they belong to as well.
+INSERT INTO users_premissions
+SELECT UP.user_id, (SELECT id FROM permissions WHERE code = 'collections:read')
+FROM users_premissions UP
+INNER JOIN permissions P ON UP.permission_id = P.id
+WHERE P.code = 'movies:read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+-- The identifiers of the movies in third-party datasets, at most one per provider. An
+-- identifier names a single movie of the catalog.
+CREATE TABLE IF NOT EXISTS external_ids (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  provider text NOT NULL CHECK (provider IN ('imdb', 'tmdb', 'wikidata')),
+  value text NOT NULL,
+  PRIMARY KEY (movie_id, provider),
+  UNIQUE (provider, value)
+);